@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"database/sql"
+	"expvar"
 	"fmt"
 	"log"
 	"os"
@@ -15,10 +16,14 @@ import (
 	"github.com/ZureTz/shorter-url/internal/api"
 	"github.com/ZureTz/shorter-url/internal/cacher"
 	"github.com/ZureTz/shorter-url/internal/service"
+	"github.com/ZureTz/shorter-url/pkg/geoip"
 	"github.com/ZureTz/shorter-url/pkg/jwt_gen"
 	"github.com/ZureTz/shorter-url/pkg/mailer"
 	"github.com/ZureTz/shorter-url/pkg/password"
+	"github.com/ZureTz/shorter-url/pkg/qrcode"
+	"github.com/ZureTz/shorter-url/pkg/screener"
 	"github.com/ZureTz/shorter-url/pkg/shortcode"
+	"github.com/ZureTz/shorter-url/pkg/urlnorm"
 	"github.com/ZureTz/shorter-url/pkg/validator"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -32,10 +37,17 @@ type App struct {
 	conf *config.Config
 
 	urlService *service.URLService
+	codePool   *service.ShortCodePool
 
-	db     *sql.DB
-	cacher *cacher.RedisCacher
-	mailer *mailer.Mailer
+	healthChecker  *service.HealthChecker
+	urlRescreener  *service.URLRescreener
+	expiryReminder *service.ExpiryReminder
+	linkPreviewer  *service.LinkPreviewWorker
+	geoLocator     *geoip.Locator
+
+	db        *sql.DB
+	cacher    *cacher.RedisCacher
+	mailQueue *service.MailQueue
 }
 
 func (a *App) Init(filePath string) error {
@@ -61,17 +73,80 @@ func (a *App) Init(filePath string) error {
 	a.cacher = cacher
 
 	// Initialize code generator
-	codeGenerator := shortcode.NewShortCodeGenerator(conf.CodeGen.ShortCodeLength)
+	codeGenerator, err := shortcode.NewShortCodeGenerator(
+		conf.CodeGen,
+		service.NewShortCodeCounter(db),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Initialize the filter for reserved and offensive short codes
+	codeFilter, err := shortcode.NewFilter(conf.CodeGen)
+	if err != nil {
+		return err
+	}
+
+	// Initialize the pool of pre-generated short codes if enabled
+	var codePool service.CodePool
+	if conf.CodePool.Enabled {
+		a.codePool = service.NewShortCodePool(db, codeGenerator, codeFilter, conf.CodePool)
+		codePool = a.codePool
+	}
+
+	// Initialize URL normalizer, falling back to the built-in tracking parameters
+	trackingParams := conf.URLService.TrackingParams
+	if len(trackingParams) == 0 {
+		trackingParams = urlnorm.DefaultTrackingParams
+	}
+	urlNormalizer := urlnorm.NewNormalizer(trackingParams)
+
+	// Initialize the screener for malicious destinations
+	urlScreener, err := screener.NewScreener(conf.Screener)
+	if err != nil {
+		return err
+	}
+
+	// Initialize the background fetcher of link previews if enabled
+	var linkPreviewer service.LinkPreviewer
+	if conf.Preview.Enabled {
+		a.linkPreviewer = service.NewLinkPreviewWorker(db, conf.Preview)
+		linkPreviewer = a.linkPreviewer
+	}
+
+	// Open the GeoIP database for redirect rules matching on country if configured
+	var geoLocator service.GeoLocator
+	if conf.GeoIP.DatabasePath != "" {
+		a.geoLocator, err = geoip.NewLocator(conf.GeoIP)
+		if err != nil {
+			return fmt.Errorf("failed to open geoip database: %w", err)
+		}
+		geoLocator = a.geoLocator
+	}
 
 	// Initialize URL service
 	urlService := service.NewURLService(
 		db,
 		cacher,
 		codeGenerator,
+		codeFilter,
+		codePool,
+		urlNormalizer,
+		urlScreener,
+		linkPreviewer,
+		geoLocator,
 		conf.URLService,
+		conf.Warmup,
 	)
 	a.urlService = urlService
 
+	// Initialize the renderer of QR codes and the service using it
+	qrRenderer, err := qrcode.NewRenderer(conf.QRCode)
+	if err != nil {
+		return fmt.Errorf("failed to initialize qr code renderer: %w", err)
+	}
+	qrCodeService := service.NewQRCodeService(db, cacher, qrRenderer, conf.URLService)
+
 	// Initialize JWT extractor for URL handler
 	jwtExtractor, err := jwt_gen.NewJWTExtractor(conf.Auth)
 	if err != nil {
@@ -79,7 +154,13 @@ func (a *App) Init(filePath string) error {
 	}
 
 	// Initialize URL handler
-	urlHandler := api.NewURLHandler(urlService, jwtExtractor)
+	urlHandler := api.NewURLHandler(urlService, qrCodeService, jwtExtractor)
+
+	// Initialize the handler of the files opening short links in mobile apps
+	wellKnownHandler, err := api.NewWellKnownHandler(conf.DeepLink)
+	if err != nil {
+		return err
+	}
 
 	// Initialize JWT generator and password manager
 	jwtGen := jwt_gen.NewJWTGenerator(conf.Auth)
@@ -88,12 +169,42 @@ func (a *App) Init(filePath string) error {
 		return err
 	}
 
-	// Initialize email sender
-	a.mailer = mailer.NewMailer(conf.Mailer)
+	// Initialize the queue of outgoing emails, rendered from the templates and sent with the configured transport
+	mailRenderer, err := mailer.NewMailer(conf.Mailer)
+	if err != nil {
+		return fmt.Errorf("failed to load email templates: %w", err)
+	}
+	mailTransport, err := mailer.NewTransport(conf.Mailer)
+	if err != nil {
+		return fmt.Errorf("failed to initialize mail transport: %w", err)
+	}
+	a.mailQueue = service.NewMailQueue(db, mailRenderer, mailTransport, conf.MailQueue)
+
+	// Initialize the background screening of the destinations of active links
+	a.urlRescreener = service.NewURLRescreener(db, cacher, urlScreener, conf.Screener)
+
+	// Initialize the health checker of link destinations
+	a.healthChecker = service.NewHealthChecker(
+		db,
+		service.NewHealthCheckHTTPClient(conf.Health.Timeout, conf.Health.AllowPrivateNetworks),
+		a.mailQueue,
+		conf.Health,
+	)
+
+	// Initialize the reminder of expiring links, its emails link back to the short link host
+	a.expiryReminder = service.NewExpiryReminder(
+		db,
+		urlService,
+		a.mailQueue,
+		jwt_gen.NewEmailActionSigner(conf.Auth, conf.Expiry.TokenExpiration),
+		conf.URLService.ShortLinkBaseURL,
+		conf.Expiry,
+	)
+	emailActionHandler := api.NewEmailActionHandler(a.expiryReminder)
 
 	// Initialize user service and handler
-	userService := service.NewUserService(a.db, cacher, jwtGen, pwdManager, a.mailer)
-	userHandler := api.NewUserHandler(userService)
+	userService := service.NewUserService(a.db, cacher, jwtGen, pwdManager, a.mailQueue, conf.Cacher.EmailCodeExpiration, conf.BruteForce)
+	userHandler := api.NewUserHandler(userService, jwtExtractor)
 
 	// Initialize Echo web framework
 	e := echo.New()
@@ -102,15 +213,48 @@ func (a *App) Init(filePath string) error {
 	e.Server.WriteTimeout = conf.Server.WriteTimeout
 	e.Server.ReadTimeout = conf.Server.ReadTimeout
 
+	// Only trust X-Forwarded-For from the configured proxies, the rate limits and the lockouts go by the address
+	ipExtractor, err := api.NewIPExtractor(conf.Server.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	e.IPExtractor = ipExtractor
+
 	// Use middleware for logging and recovering from panics
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
+	// Throttle requests with the budgets of the route policies if enabled
+	if conf.RateLimit.Enabled {
+		rateLimiter, err := service.NewRateLimiter(cacher, conf.RateLimit)
+		if err != nil {
+			return err
+		}
+		e.Use(api.NewRateLimitMiddleware(rateLimiter, jwtExtractor, conf.RateLimit.APIKeyHeader))
+	}
+
 	// Add request validation middleware
-	e.Validator = validator.NewValidator()
+	e.Validator = validator.NewValidator(conf.CodeGen.ShortCodeLength)
+
+	// Add renderer for the HTML pages
+	renderer, err := api.NewTemplateRenderer()
+	if err != nil {
+		return err
+	}
+	e.Renderer = renderer
+
+	// Serve runtime metrics if enabled
+	if conf.Server.ExposeMetrics {
+		e.GET("/debug/vars", echo.WrapHandler(expvar.Handler()))
+	}
 
 	// Register routes
 	e.GET("/:short_code", urlHandler.RedirectToOriginalURL)
+	e.GET("/preview/:short_code", urlHandler.PreviewShortURL)
+
+	// For opening short links in mobile apps
+	e.GET("/.well-known/apple-app-site-association", wellKnownHandler.AppleAppSiteAssociation)
+	e.GET("/.well-known/assetlinks.json", wellKnownHandler.AndroidAssetLinks)
 
 	// For user and authentication controller
 	e.POST("/api/login", userHandler.UserLogin)
@@ -118,6 +262,12 @@ func (a *App) Init(filePath string) error {
 	e.POST("/api/email_code", userHandler.GetEmailCode)
 	e.PUT("/api/reset_password", userHandler.ResetPassword)
 
+	// For the links in emails, which are confirmed with a form before anything changes
+	e.GET("/api/email/extend", emailActionHandler.ConfirmExtendLink)
+	e.POST("/api/email/extend", emailActionHandler.ExtendLink)
+	e.GET("/api/email/unsubscribe", emailActionHandler.ConfirmUnsubscribe)
+	e.POST("/api/email/unsubscribe", emailActionHandler.Unsubscribe)
+
 	r := e.Group("/api/user")
 	// Add JWT middleware for protected routes
 	config := echoJWT.Config{
@@ -128,6 +278,8 @@ func (a *App) Init(filePath string) error {
 		TokenLookup: "cookie:token", // Look for JWT in the cookie named "token"
 	}
 	r.Use(echoJWT.WithConfig(config))
+	// Tokens outlive deleted accounts, and must not pass for a new account with the same username
+	r.Use(api.NewTokenUserMiddleware(userService, jwtExtractor))
 
 	// For testing user authentication
 	r.GET("/test_auth", userHandler.TestAuth)
@@ -137,6 +289,16 @@ func (a *App) Init(filePath string) error {
 	r.GET("/my_urls", urlHandler.GetMyURLs)
 	// For deleting a short URL
 	r.DELETE("/url", urlHandler.DeleteShortURL)
+	// For getting the QR code of a short URL
+	r.GET("/url/:id/qr", urlHandler.GetMyURLQRCode)
+	// For changing the email, with codes sent to the current and the new email
+	r.POST("/email_code", userHandler.RequestEmailChange)
+	r.PUT("/email", userHandler.ChangeEmail)
+	// For reading and updating the profile and the preferences
+	r.GET("/me", userHandler.GetProfile)
+	r.PUT("/me", userHandler.UpdateProfile)
+	// For deleting the account
+	r.DELETE("/me", userHandler.DeleteAccount)
 
 	// Bind the URL handler to the Echo instance
 	a.e = e
@@ -149,9 +311,40 @@ func (a *App) Run() {
 	// Start the server
 	go a.startServer()
 
+	// Send the queued emails
+	a.mailQueue.Start()
+
 	// Start the cleanup routine for outdated URLs
 	go a.cleanUp()
 
+	// Keep the pool of pre-generated short codes filled
+	if a.codePool != nil {
+		go a.refillCodePool()
+	}
+
+	// Check the destinations of active links
+	if a.conf.Health.Enabled {
+		go a.checkHealth()
+	}
+
+	// Screen the destinations of active links again
+	if a.conf.Screener.Enabled {
+		go a.rescreenURLs()
+	}
+
+	// Remind owners of their links expiring soon
+	if a.conf.Expiry.Enabled {
+		go a.remindExpiringLinks()
+	}
+
+	// Add the clicks of variants counted in redis to the database
+	go a.flushVariantClicks()
+
+	// Preload the cache on startup and re-warm it periodically
+	if a.conf.Warmup.Enabled {
+		go a.warmUpCache()
+	}
+
 	// Handle graceful shutdown
 	a.stopServer()
 }
@@ -172,25 +365,133 @@ func (a *App) cleanUp() {
 	}
 }
 
-func (a *App) stopServer() {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+func (a *App) refillCodePool() {
+	// Fill the pool right away instead of waiting for the first tick
+	if err := a.codePool.Refill(context.Background()); err != nil {
+		log.Println(err)
+	}
 
-	defer func() {
-		if err := a.db.Close(); err != nil {
-			log.Printf("Error closing database connection: %v", err)
+	ticker := time.NewTicker(a.codePool.RefillInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.codePool.Refill(context.Background()); err != nil {
+			log.Println(err)
 		}
-	}()
+	}
+}
+
+func (a *App) checkHealth() {
+	ticker := time.NewTicker(a.healthChecker.Interval())
+	defer ticker.Stop()
 
-	defer func() {
-		if err := a.cacher.Close(); err != nil {
-			log.Printf("Error closing cacher connection: %v", err)
+	for range ticker.C {
+		if err := a.healthChecker.CheckDueURLs(context.Background()); err != nil {
+			log.Println(err)
 		}
-	}()
+	}
+}
 
-	// Close channel to stop the mailer daemon
-	defer a.mailer.StopDaemon()
+func (a *App) rescreenURLs() {
+	ticker := time.NewTicker(a.urlRescreener.Interval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.urlRescreener.RescreenDueURLs(context.Background()); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (a *App) remindExpiringLinks() {
+	ticker := time.NewTicker(a.expiryReminder.Interval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.expiryReminder.RemindExpiringLinks(context.Background()); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (a *App) flushVariantClicks() {
+	ticker := time.NewTicker(a.urlService.VariantClickFlushInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.urlService.FlushVariantClicks(context.Background()); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (a *App) warmUpCache() {
+	if err := a.urlService.WarmUpCache(context.Background()); err != nil {
+		log.Println(err)
+	}
+
+	// Only warm up once if no interval is configured
+	if a.conf.Warmup.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(a.conf.Warmup.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := a.urlService.WarmUpCache(context.Background()); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// RunCommand runs a one-off administrative command instead of starting the server
+func (a *App) RunCommand(command string) error {
+	defer a.closeConnections()
+
+	switch command {
+	case "warmup":
+		// Re-warm the cache of a running deployment
+		return a.urlService.WarmUpCache(context.Background())
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
+
+// closeConnections releases the database and cacher resources
+func (a *App) closeConnections() {
+	// Finish fetching the queued link previews before closing the database
+	if a.linkPreviewer != nil {
+		a.linkPreviewer.Stop()
+	}
+
+	// Keep the clicks counted since the last flush
+	if err := a.urlService.FlushVariantClicks(context.Background()); err != nil {
+		log.Printf("Error flushing variant clicks: %v", err)
+	}
+
+	if a.geoLocator != nil {
+		if err := a.geoLocator.Close(); err != nil {
+			log.Printf("Error closing geoip database: %v", err)
+		}
+	}
+
+	if err := a.cacher.Close(); err != nil {
+		log.Printf("Error closing cacher connection: %v", err)
+	}
+
+	if err := a.db.Close(); err != nil {
+		log.Printf("Error closing database connection: %v", err)
+	}
+}
+
+func (a *App) stopServer() {
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// Release the connections after the server has shut down
+	defer a.closeConnections()
 
 	// Wait for the server to gracefully shut down after finishing all requests
 	ctx, cancel := context.WithTimeout(context.Background(), a.conf.Server.GracefulShutdownTimeout)
@@ -199,4 +500,12 @@ func (a *App) stopServer() {
 	if err := a.e.Shutdown(ctx); err != nil {
 		log.Println(err)
 	}
+
+	// Send the emails that are due before closing the database, the rest are sent after the next start
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), a.conf.MailQueue.DrainTimeout)
+	defer drainCancel()
+
+	if err := a.mailQueue.Stop(drainCtx); err != nil {
+		log.Printf("Error draining the mail queue: %v", err)
+	}
 }
@@ -49,16 +49,143 @@ type CacherConfig struct {
 
 	// User caching related
 	EmailCodeExpiration time.Duration `mapstructure:"email_code_expiration"`
+	// Minimum time between two codes sent to the same email
+	EmailCodeCooldown time.Duration `mapstructure:"email_code_cooldown"`
+
+	// How long the result of a request with an Idempotency-Key header is kept, 24 hours if unset
+	IdempotencyKeyExpiration time.Duration `mapstructure:"idempotency_key_expiration"`
+
+	// How long rendered QR code images are kept
+	QRCodeExpiration time.Duration `mapstructure:"qr_code_expiration"`
+}
+
+type CacheWarmupConfig struct {
+	// Whether to preload URLs into the cache on startup
+	Enabled bool `mapstructure:"enabled"`
+	// Number of most recently created URLs to preload
+	RecentLimit int `mapstructure:"recent_limit"`
+	// Number of most clicked URLs to preload, tracked from redirects
+	HotLimit int `mapstructure:"hot_limit"`
+	// Maximum number of short codes kept in the click ranking
+	HotKeysTracked int `mapstructure:"hot_keys_tracked"`
+	// Number of URLs written to the cache per pipelined batch
+	BatchSize int `mapstructure:"batch_size"`
+	// Interval between background re-warms, set to 0 to only warm up on startup
+	Interval time.Duration `mapstructure:"interval"`
 }
 
 type CodeGeneratorConfig struct {
-	ShortCodeLength int `mapstructure:"short_code_length"`
+	// Strategy used to generate short codes: "random", "counter" or "snowflake"
+	Strategy        string `mapstructure:"strategy"`
+	ShortCodeLength int    `mapstructure:"short_code_length"`
+	// Alphabet profile: "base62", "unambiguous", "lowercase" or "custom"
+	AlphabetProfile string `mapstructure:"alphabet_profile"`
+	// Characters used in short codes by the custom profile
+	Alphabet string `mapstructure:"alphabet"`
+	// Words that must not appear in generated or custom codes, in addition to the default list
+	Blocklist               []string `mapstructure:"blocklist"`
+	BlocklistFile           string   `mapstructure:"blocklist_file"`
+	DisableDefaultBlocklist bool     `mapstructure:"disable_default_blocklist"`
+	// Words containing a blocked word that are allowed anyway, in addition to the default list
+	Allowlist []string `mapstructure:"allowlist"`
+	// Codes that cannot be used, in addition to paths like api, admin and static
+	ReservedCodes []string `mapstructure:"reserved_codes"`
+	// Secret key of the permutation obfuscating counter and snowflake based codes, required by those strategies
+	PermutationKey      string `mapstructure:"permutation_key"`
+	SnowflakeNodeNumber int    `mapstructure:"snowflake_node_number"`
+}
+
+type ShortCodePoolConfig struct {
+	// Whether short codes are handed out from the pre-generated pool
+	Enabled bool `mapstructure:"enabled"`
+	// The pool is refilled to the target size once it drops below the low-water mark
+	LowWaterMark int `mapstructure:"low_water_mark"`
+	TargetSize   int `mapstructure:"target_size"`
+	// Interval between checks of the pool size
+	RefillInterval time.Duration `mapstructure:"refill_interval"`
 }
 
 type URLServiceConfig struct {
 	ShortLinkBaseURL           string        `mapstructure:"short_link_base_url"`
 	DefaultExpiration          time.Duration `mapstructure:"default_expiration"`
 	OutdatedURLCleanupInterval time.Duration `mapstructure:"outdated_url_cleanup_interval"`
+	// How often the clicks of variants counted in redis are added to the database
+	VariantClickFlushInterval time.Duration `mapstructure:"variant_click_flush_interval"`
+	// Query parameters removed from the canonical URL, a trailing "*" matches a prefix
+	TrackingParams []string `mapstructure:"tracking_params"`
+}
+
+type ScreenerConfig struct {
+	// Whether destinations are screened for malware and phishing
+	Enabled bool `mapstructure:"enabled"`
+	// Local blocklists, the domains also block their subdomains
+	BlockedDomains  []string `mapstructure:"blocked_domains"`
+	BlockedPatterns []string `mapstructure:"blocked_patterns"`
+	// Files in hosts file format, or with one domain per line
+	HostsFiles []string `mapstructure:"hosts_files"`
+	// Safe-Browsing-style threatMatches:find endpoint, leave empty to only use local blocklists
+	LookupEndpoint string        `mapstructure:"lookup_endpoint"`
+	LookupAPIKey   string        `mapstructure:"lookup_api_key"`
+	LookupTimeout  time.Duration `mapstructure:"lookup_timeout"`
+	LookupCacheTTL time.Duration `mapstructure:"lookup_cache_ttl"`
+	ThreatTypes    []string      `mapstructure:"threat_types"`
+	// Treat URLs as clean when the lookup endpoint cannot be reached
+	FailOpen bool `mapstructure:"fail_open"`
+	// Destinations of active links are screened again in the background every rescreen_after
+	RescreenInterval  time.Duration `mapstructure:"rescreen_interval"`
+	RescreenAfter     time.Duration `mapstructure:"rescreen_after"`
+	RescreenBatchSize int           `mapstructure:"rescreen_batch_size"`
+}
+
+type HealthCheckConfig struct {
+	// Whether destinations of active links are checked in the background
+	Enabled bool `mapstructure:"enabled"`
+	// Interval between runs of the checker
+	Interval time.Duration `mapstructure:"interval"`
+	// Minimum time between two checks of the same link, 24 hours if unset
+	RecheckAfter time.Duration `mapstructure:"recheck_after"`
+	// Maximum number of links checked per run, and how many are checked at once
+	BatchSize   int `mapstructure:"batch_size"`
+	Concurrency int `mapstructure:"concurrency"`
+	// Maximum requests per second sent to the same host, 0 for no limit
+	PerHostRate float64 `mapstructure:"per_host_rate"`
+	// Timeout of each request, and the number of redirects followed, 10 if unset
+	Timeout      time.Duration `mapstructure:"timeout"`
+	MaxRedirects int           `mapstructure:"max_redirects"`
+	UserAgent    string        `mapstructure:"user_agent"`
+	// Owners are emailed once a link failed this many checks in a row
+	NotifyOwners     bool `mapstructure:"notify_owners"`
+	FailureThreshold int  `mapstructure:"failure_threshold"`
+	// Allow checking destinations on loopback and private networks
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+}
+
+type ExpiryReminderConfig struct {
+	// Whether owners are emailed a digest of their links expiring soon
+	Enabled bool `mapstructure:"enabled"`
+	// Interval between runs, and how long before the expiry links are included
+	Interval     time.Duration `mapstructure:"interval"`
+	RemindBefore time.Duration `mapstructure:"remind_before"`
+	// Maximum number of links reminded of per run
+	BatchSize int `mapstructure:"batch_size"`
+	// How much the extend link in the email adds to the expiry, the default expiration if 0
+	ExtendBy time.Duration `mapstructure:"extend_by"`
+	// How long the extend and unsubscribe links in the email work
+	TokenExpiration time.Duration `mapstructure:"token_expiration"`
+}
+
+type LinkPreviewConfig struct {
+	// Whether the title, description and Open Graph tags of new links are fetched in the background
+	Enabled bool `mapstructure:"enabled"`
+	// Timeout of each fetch, and the maximum number of bytes read from the page
+	Timeout      time.Duration `mapstructure:"timeout"`
+	MaxBodyBytes int64         `mapstructure:"max_body_bytes"`
+	UserAgent    string        `mapstructure:"user_agent"`
+	// Number of links waiting to be fetched, and how many are fetched at once
+	QueueSize int `mapstructure:"queue_size"`
+	Workers   int `mapstructure:"workers"`
+	// Allow fetching pages on loopback and private networks
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
 }
 
 type PasswordManagerConfig struct {
@@ -66,11 +193,68 @@ type PasswordManagerConfig struct {
 	PasswordHashCost  int `mapstructure:"password_hash_cost"`
 }
 
+type DeepLinkConfig struct {
+	// Apple app IDs (team ID and bundle ID) opened by short links as universal links
+	AppleAppIDs []string `mapstructure:"apple_app_ids"`
+	// Path patterns claimed for universal links, every short URL if empty
+	ApplePaths []string `mapstructure:"apple_paths"`
+	// Android package opened by short links as app links, and the SHA-256 fingerprints of its signing certificates
+	AndroidPackageName      string   `mapstructure:"android_package_name"`
+	AndroidCertFingerprints []string `mapstructure:"android_cert_fingerprints"`
+}
+
+type GeoIPConfig struct {
+	// Path of a MaxMind-format country or city database, e.g. GeoLite2-Country.mmdb
+	// Redirect rules matching on country never match if it is empty
+	DatabasePath string `mapstructure:"database_path"`
+}
+
+type QRCodeConfig struct {
+	// Defaults of the options clients can change
+	DefaultSize  int    `mapstructure:"default_size"`
+	DefaultLevel string `mapstructure:"default_level"`
+	Foreground   string `mapstructure:"foreground"`
+	Background   string `mapstructure:"background"`
+	Margin       int    `mapstructure:"margin"`
+	// Largest image clients can request, in pixels
+	MaxSize int `mapstructure:"max_size"`
+	// PNG or JPEG image drawn in the center on request, and its share of the width of the code
+	LogoFile  string  `mapstructure:"logo_file"`
+	LogoRatio float64 `mapstructure:"logo_ratio"`
+}
+
+type RateLimitPolicy struct {
+	Name string `mapstructure:"name"`
+	// Routes limited by the policy as "METHOD /path" in route syntax, e.g. "GET /:short_code", or "*" for every other route
+	Routes []string `mapstructure:"routes"`
+	// Requests allowed per window by IP address, user and API key, set to 0 for no limit
+	Anonymous     int           `mapstructure:"anonymous"`
+	Authenticated int           `mapstructure:"authenticated"`
+	APIKey        int           `mapstructure:"api_key"`
+	Window        time.Duration `mapstructure:"window"`
+}
+
+type RateLimitConfig struct {
+	// Whether requests are throttled
+	Enabled bool `mapstructure:"enabled"`
+	// Header carrying API keys, and the keys given the API key budget
+	APIKeyHeader string   `mapstructure:"api_key_header"`
+	APIKeys      []string `mapstructure:"api_keys"`
+	// Let requests through when redis cannot be reached
+	FailOpen bool              `mapstructure:"fail_open"`
+	Policies []RateLimitPolicy `mapstructure:"policies"`
+}
+
 type ServerConfig struct {
 	Port                    int           `mapstructure:"port"`
 	WriteTimeout            time.Duration `mapstructure:"write_timeout"`
 	ReadTimeout             time.Duration `mapstructure:"read_timeout"`
 	GracefulShutdownTimeout time.Duration `mapstructure:"graceful_shutdown_timeout"`
+	// Serve runtime metrics at /debug/vars
+	ExposeMetrics bool `mapstructure:"expose_metrics"`
+	// Addresses or CIDR ranges of the reverse proxies allowed to set X-Forwarded-For
+	// The address of the connection is used as the client's if empty
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type AuthConfig struct {
@@ -78,22 +262,84 @@ type AuthConfig struct {
 	JWTExpiration time.Duration `mapstructure:"jwt_expiration"`
 }
 
+type BruteForceConfig struct {
+	// Failed logins allowed per account and per IP address within the window before a lockout, set to -1 for no limit
+	MaxAccountFailures int           `mapstructure:"max_account_failures"`
+	MaxIPFailures      int           `mapstructure:"max_ip_failures"`
+	FailureWindow      time.Duration `mapstructure:"failure_window"`
+	// The first lockout lasts the base duration, each further one within the memory twice as long, up to the maximum
+	BaseLockout   time.Duration `mapstructure:"base_lockout"`
+	MaxLockout    time.Duration `mapstructure:"max_lockout"`
+	LockoutMemory time.Duration `mapstructure:"lockout_memory"`
+	// Wrong tries after which an email code is invalidated, and wrong codes allowed per IP address before a lockout, -1 for no limit
+	MaxEmailCodeAttempts   int `mapstructure:"max_email_code_attempts"`
+	MaxEmailCodeIPFailures int `mapstructure:"max_email_code_ip_failures"`
+	// Email the owner of an account when it gets locked
+	NotifyOwner bool `mapstructure:"notify_owner"`
+}
+
 type MailerConfig struct {
-	SMTPHost string `mapstructure:"smtp_host"`
-	SMTPPort int    `mapstructure:"smtp_port"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	FromMail string `mapstructure:"from_mail"`
+	// Transport delivering the emails: "smtp", "log", "file" or "http"
+	Transport string `mapstructure:"transport"`
+	FromMail  string `mapstructure:"from_mail"`
+	SMTPHost  string `mapstructure:"smtp_host"`
+	SMTPPort  int    `mapstructure:"smtp_port"`
+	Username  string `mapstructure:"username"`
+	Password  string `mapstructure:"password"`
+	// "starttls" upgrades the connection when the server supports it, "tls" connects with TLS right away
+	SMTPSecurity           string `mapstructure:"smtp_security"`
+	SMTPInsecureSkipVerify bool   `mapstructure:"smtp_insecure_skip_verify"`
+	// Deadline for connecting to the SMTP server and for sending each email
+	SMTPTimeout time.Duration `mapstructure:"smtp_timeout"`
+	// Directory the file transport writes emails to, as .eml files or as a maildir
+	FileDir    string `mapstructure:"file_dir"`
+	FileFormat string `mapstructure:"file_format"`
+	// Endpoint the http transport posts emails to as JSON, with the API key as a bearer token
+	HTTPEndpoint string        `mapstructure:"http_endpoint"`
+	HTTPAPIKey   string        `mapstructure:"http_api_key"`
+	HTTPTimeout  time.Duration `mapstructure:"http_timeout"`
+	// Directory with templates overriding or adding to the embedded ones, as <language>/<name>.txt and .html files
+	TemplateDir string `mapstructure:"template_dir"`
+	// Language of the emails to people whose language has no templates
+	DefaultLanguage string `mapstructure:"default_language"`
+}
+
+type MailQueueConfig struct {
+	// Interval between checks for due emails, queued emails are also sent right away
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Number of emails claimed at once, and how long other instances skip them
+	BatchSize   int           `mapstructure:"batch_size"`
+	LockTimeout time.Duration `mapstructure:"lock_timeout"`
+	// Failed sends are retried after the base delay, doubling each time up to the maximum
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `mapstructure:"retry_max_delay"`
+	// How long due emails are still sent on shutdown
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// Dead letters older than this are deleted, 30 days if unset
+	DeadLetterRetention time.Duration `mapstructure:"dead_letter_retention"`
 }
 
 type Config struct {
 	DB         DBConfig              `mapstructure:"db"`
 	Cacher     CacherConfig          `mapstructure:"cacher"`
+	Warmup     CacheWarmupConfig     `mapstructure:"cache_warmup"`
 	CodeGen    CodeGeneratorConfig   `mapstructure:"code_generator"`
+	CodePool   ShortCodePoolConfig   `mapstructure:"short_code_pool"`
 	PwdManager PasswordManagerConfig `mapstructure:"password_manager"`
 	Auth       AuthConfig            `mapstructure:"auth"`
+	BruteForce BruteForceConfig      `mapstructure:"brute_force"`
 	Mailer     MailerConfig          `mapstructure:"mailer"`
+	MailQueue  MailQueueConfig       `mapstructure:"mail_queue"`
 	URLService URLServiceConfig      `mapstructure:"url_service"`
+	Screener   ScreenerConfig        `mapstructure:"screener"`
+	Health     HealthCheckConfig     `mapstructure:"health_check"`
+	Expiry     ExpiryReminderConfig  `mapstructure:"expiry_reminder"`
+	Preview    LinkPreviewConfig     `mapstructure:"link_preview"`
+	GeoIP      GeoIPConfig           `mapstructure:"geoip"`
+	DeepLink   DeepLinkConfig        `mapstructure:"deep_link"`
+	QRCode     QRCodeConfig          `mapstructure:"qr_code"`
+	RateLimit  RateLimitConfig       `mapstructure:"rate_limit"`
 	Server     ServerConfig          `mapstructure:"server"`
 }
 
@@ -0,0 +1,10 @@
+-- Pool of pre-generated short codes that are not used by any URL yet.
+-- Codes are handed out atomically when short URLs are created, so no collision check is needed on that path.
+create table
+  if not exists short_code_pool (
+    code text primary key,
+    created_at timestamp not null default current_timestamp
+  );
+
+-- Index for handing out the oldest codes first
+create index idx_short_code_pool_created_at on short_code_pool (created_at);
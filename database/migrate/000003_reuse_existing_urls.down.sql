@@ -0,0 +1,4 @@
+drop index if exists idx_urls_created_by_original_url;
+
+alter table users
+drop column if exists reuse_existing_urls;
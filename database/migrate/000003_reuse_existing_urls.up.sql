@@ -0,0 +1,6 @@
+-- Whether creating a short URL for an already shortened target returns the existing short URL
+alter table users
+add column if not exists reuse_existing_urls boolean not null default false;
+
+-- Index for finding a user's existing short URL of a target
+create index idx_urls_created_by_original_url on urls (created_by, original_url);
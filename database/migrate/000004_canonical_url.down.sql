@@ -0,0 +1,5 @@
+drop index if exists idx_urls_created_by_canonical_url;
+create index idx_urls_created_by_original_url on urls (created_by, original_url);
+
+alter table urls
+drop column if exists canonical_url;
@@ -0,0 +1,17 @@
+-- Normalized form of the original URL, used to recognize different spellings of the same target
+alter table urls
+add column if not exists canonical_url text;
+
+-- Existing URLs were stored as submitted
+update urls
+set
+  canonical_url = original_url
+where
+  canonical_url is null;
+
+alter table urls
+alter column canonical_url set not null;
+
+-- Existing short URLs of a target are now found by the canonical URL
+drop index if exists idx_urls_created_by_original_url;
+create index idx_urls_created_by_canonical_url on urls (created_by, canonical_url);
@@ -0,0 +1,3 @@
+alter table urls
+drop column if exists quarantined_at,
+drop column if exists quarantine_reason;
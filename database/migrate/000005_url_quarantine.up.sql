@@ -0,0 +1,4 @@
+-- Links flagged as malicious or phishing are quarantined and show a warning page instead of redirecting
+alter table urls
+add column if not exists quarantined_at timestamp,
+add column if not exists quarantine_reason text not null default '';
@@ -0,0 +1,19 @@
+-- Result of the latest health check of each short URL's destination
+create table
+  if not exists url_health_checks (
+    url_id bigint primary key references urls (id) on delete cascade,
+    -- Status code of the final response, 0 if no response was received
+    status_code integer not null default 0,
+    latency_ms integer not null default 0,
+    -- URLs visited while following redirects, starting with the original URL
+    redirect_chain jsonb not null default '[]',
+    error text not null default '',
+    is_broken boolean not null default false,
+    consecutive_failures integer not null default 0,
+    checked_at timestamp not null default current_timestamp,
+    -- When the owner was emailed about the broken link, reset once the link works again
+    notified_at timestamp
+  );
+
+-- Index for finding the URLs that are due for a check
+create index idx_url_health_checks_checked_at on url_health_checks (checked_at);
@@ -0,0 +1,15 @@
+-- Metadata of the page behind each short URL, fetched in the background when the URL is created
+create table
+  if not exists url_previews (
+    url_id bigint primary key references urls (id) on delete cascade,
+    title text not null default '',
+    description text not null default '',
+    og_title text not null default '',
+    og_description text not null default '',
+    og_image text not null default '',
+    og_site_name text not null default '',
+    favicon_url text not null default '',
+    -- Why the page could not be fetched, empty on success
+    fetch_error text not null default '',
+    fetched_at timestamp not null default current_timestamp
+  );
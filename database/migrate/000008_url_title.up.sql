@@ -0,0 +1,3 @@
+-- Title given by the owner, shown on the preview page of the short URL
+alter table urls
+add column if not exists title text not null default '';
@@ -0,0 +1,2 @@
+alter table urls
+drop column if exists redirect_type;
@@ -0,0 +1,3 @@
+-- How the short URL redirects: with a 301, 302, 307 or 308 status, or with a meta refresh page
+alter table urls
+add column if not exists redirect_type text not null default '302' check (redirect_type in ('301', '302', '307', '308', 'meta'));
@@ -0,0 +1,7 @@
+alter table urls
+drop column if exists utm_source,
+drop column if exists utm_medium,
+drop column if exists utm_campaign,
+drop column if exists utm_term,
+drop column if exists utm_content,
+drop column if exists query_passthrough;
@@ -0,0 +1,9 @@
+-- UTM parameters added to the destination when redirecting
+alter table urls
+add column if not exists utm_source text not null default '',
+add column if not exists utm_medium text not null default '',
+add column if not exists utm_campaign text not null default '',
+add column if not exists utm_term text not null default '',
+add column if not exists utm_content text not null default '',
+-- Whether the query string of the short URL is forwarded to the destination
+add column if not exists query_passthrough text not null default 'none' check (query_passthrough in ('none', 'append', 'override'));
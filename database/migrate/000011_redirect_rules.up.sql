@@ -0,0 +1,3 @@
+-- Ordered rules sending visitors to other destinations by country, device, language or time
+alter table urls
+add column if not exists redirect_rules jsonb not null default '[]';
@@ -0,0 +1,14 @@
+-- Weighted destinations splitting the traffic of a short URL for A/B tests
+create table
+  if not exists url_variants (
+    id bigserial primary key,
+    url_id bigint not null references urls (id) on delete cascade,
+    destination text not null,
+    weight integer not null check (weight > 0),
+    -- Number of redirects to this destination
+    clicks bigint not null default 0,
+    created_at timestamp not null default current_timestamp
+  );
+
+-- Index for finding the variants of a short URL
+create index idx_url_variants_url_id on url_variants (url_id);
@@ -0,0 +1,3 @@
+alter table urls
+drop column if exists ios_deep_link,
+drop column if exists android_deep_link;
@@ -0,0 +1,4 @@
+-- Links opening the native app on iOS and Android, the destination is the fallback if the app is not installed
+alter table urls
+add column if not exists ios_deep_link text not null default '',
+add column if not exists android_deep_link text not null default '';
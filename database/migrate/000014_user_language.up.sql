@@ -0,0 +1,3 @@
+-- Language of the emails sent to the user
+alter table users
+add column if not exists language text not null default 'en';
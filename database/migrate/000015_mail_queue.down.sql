@@ -0,0 +1,3 @@
+drop table if exists mail_dead_letters;
+
+drop table if exists mail_queue;
@@ -0,0 +1,33 @@
+-- Outgoing emails, kept until they are sent so that none are lost on restarts
+create table
+  if not exists mail_queue (
+    id bigserial primary key,
+    recipient text not null,
+    subject text not null,
+    text_body text not null,
+    html_body text not null,
+    -- Failed sends so far, and the error of the last one
+    attempts integer not null default 0,
+    last_error text not null default '',
+    next_attempt_at timestamp not null default current_timestamp,
+    -- Set while a worker sends the email, so that other workers skip it
+    locked_until timestamp,
+    created_at timestamp not null default current_timestamp
+  );
+
+-- Index for finding the emails due to be sent
+create index idx_mail_queue_next_attempt_at on mail_queue (next_attempt_at);
+
+-- Emails that could not be sent after the maximum number of attempts
+create table
+  if not exists mail_dead_letters (
+    id bigserial primary key,
+    recipient text not null,
+    subject text not null,
+    text_body text not null,
+    html_body text not null,
+    attempts integer not null,
+    last_error text not null,
+    queued_at timestamp not null,
+    failed_at timestamp not null default current_timestamp
+  );
@@ -0,0 +1,5 @@
+alter table urls
+drop column if exists expiry_reminded_at;
+
+alter table users
+drop column if exists expiry_reminders;
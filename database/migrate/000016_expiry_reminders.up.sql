@@ -0,0 +1,7 @@
+-- When the owner was last reminded that the link expires soon, cleared when the link is extended
+alter table urls
+add column if not exists expiry_reminded_at timestamp;
+
+-- Whether the user gets emails about links expiring soon
+alter table users
+add column if not exists expiry_reminders boolean not null default true;
@@ -0,0 +1,5 @@
+alter table users
+drop column if exists display_name,
+drop column if exists timezone,
+drop column if exists default_duration,
+drop column if exists default_redirect_type;
@@ -0,0 +1,8 @@
+-- Profile and preferences of the user
+alter table users
+add column if not exists display_name text not null default '',
+-- IANA time zone the times in emails are shown in
+add column if not exists timezone text not null default 'UTC',
+-- Defaults of the short URLs created by the user, the server defaults are used if not set
+add column if not exists default_duration integer check (default_duration between 1 and 720),
+add column if not exists default_redirect_type text not null default '' check (default_redirect_type in ('', '301', '302', '307', '308', 'meta'));
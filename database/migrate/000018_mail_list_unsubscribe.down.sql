@@ -0,0 +1,2 @@
+alter table mail_queue
+drop column if exists list_unsubscribe;
@@ -0,0 +1,3 @@
+-- One-click unsubscribe address sent in the List-Unsubscribe header (RFC 8058), empty if the email has none
+alter table mail_queue
+add column if not exists list_unsubscribe text not null default '';
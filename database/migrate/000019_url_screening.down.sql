@@ -0,0 +1,4 @@
+drop index if exists idx_urls_screened_at;
+
+alter table urls
+drop column if exists screened_at;
@@ -0,0 +1,10 @@
+-- When the destinations of the link were last screened, links are screened again in the background
+-- Existing links were never screened since they were created, new ones are screened when they are created
+alter table urls
+add column if not exists screened_at timestamp;
+
+alter table urls
+alter column screened_at set default current_timestamp;
+
+-- Index for finding the links due to be screened again
+create index if not exists idx_urls_screened_at on urls (screened_at nulls first);
@@ -0,0 +1 @@
+drop sequence if exists short_code_counter;
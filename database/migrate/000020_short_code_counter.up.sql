@@ -0,0 +1,2 @@
+-- Numbers of the counter short code strategy, a sequence survives a flushed or failed over cache
+create sequence if not exists short_code_counter;
@@ -0,0 +1,4 @@
+drop index if exists idx_mail_dead_letters_failed_at;
+
+alter table mail_queue
+drop column if exists expires_at;
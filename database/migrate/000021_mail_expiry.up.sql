@@ -0,0 +1,6 @@
+-- Emails that are useless after a while, like email codes, are dropped instead of sent late, null if they do not expire
+alter table mail_queue
+add column if not exists expires_at timestamp;
+
+-- Index for purging dead letters past their retention
+create index if not exists idx_mail_dead_letters_failed_at on mail_dead_letters (failed_at);
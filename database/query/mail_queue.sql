@@ -0,0 +1,97 @@
+-- name: EnqueueMail :exec
+insert into mail_queue (
+  recipient,
+  subject,
+  text_body,
+  html_body,
+  list_unsubscribe,
+  expires_at
+) values (
+  $1, $2, $3, $4, $5, $6
+);
+
+-- name: ClaimDueMail :many
+update mail_queue
+set
+  locked_until = $1
+where
+  id in (
+    select
+      id
+    from
+      mail_queue
+    where
+      next_attempt_at <= current_timestamp
+      and (
+        locked_until is null
+        or
+        locked_until <= current_timestamp
+      )
+    order by
+      next_attempt_at
+    limit $2
+    for update skip locked
+  )
+returning *;
+
+-- name: DeleteMail :exec
+delete from
+  mail_queue
+where
+  id = $1
+;
+
+-- name: RetryMail :exec
+update mail_queue
+set
+  attempts = attempts + 1,
+  last_error = $2,
+  next_attempt_at = $3,
+  locked_until = null
+where
+  id = $1
+;
+
+-- name: ReleaseMail :exec
+update mail_queue
+set
+  locked_until = null
+where
+  id = $1
+;
+
+-- name: MoveMailToDeadLetters :exec
+with failed as (
+  delete from
+    mail_queue
+  where
+    id = $1
+  returning *
+)
+insert into mail_dead_letters (
+  recipient,
+  subject,
+  text_body,
+  html_body,
+  attempts,
+  last_error,
+  queued_at
+)
+select
+  recipient,
+  subject,
+  text_body,
+  html_body,
+  attempts + 1,
+  $2,
+  created_at
+from
+  failed
+;
+
+-- name: DeleteOldDeadLetters :execrows
+delete from
+  mail_dead_letters
+where
+  failed_at < $1
+;
@@ -0,0 +1,4 @@
+-- name: NextShortCodeNumber :one
+select
+  nextval('short_code_counter')::bigint as number
+;
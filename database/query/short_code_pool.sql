@@ -0,0 +1,57 @@
+-- name: TakePooledShortCode :one
+delete from
+  short_code_pool
+where
+  code = (
+    select
+      code
+    from
+      short_code_pool
+    order by
+      created_at
+    limit 1
+    for update skip locked
+  )
+returning code
+;
+
+-- name: AddShortCodeToPool :execrows
+insert into short_code_pool (
+  code
+)
+select
+  sqlc.arg(code)::text
+where
+  not exists (
+    select
+      1
+    from
+      urls
+    where
+      short_code = sqlc.arg(code)::text
+  )
+on conflict (code) do nothing
+;
+
+-- name: DeleteUsedPooledShortCodes :execrows
+delete from
+  short_code_pool p
+using
+  urls u
+where
+  u.short_code = p.code
+;
+
+-- name: CountPooledShortCodes :one
+select
+  count(*)
+from
+  short_code_pool
+;
+
+-- name: RemoveShortCodeFromPool :exec
+delete from
+  short_code_pool
+where
+  code = $1
+;
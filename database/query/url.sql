@@ -4,9 +4,23 @@ insert into urls (
   short_code,
   is_custom,
   expired_at,
-  created_by
+  created_by,
+  canonical_url,
+  quarantined_at,
+  quarantine_reason,
+  title,
+  redirect_type,
+  utm_source,
+  utm_medium,
+  utm_campaign,
+  utm_term,
+  utm_content,
+  query_passthrough,
+  redirect_rules,
+  ios_deep_link,
+  android_deep_link
 ) values (
-  $1, $2, $3, $4, $5
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
 ) returning *;
 
 -- name: IsShortCodeAvailable :one
@@ -59,6 +73,17 @@ where
   expired_at <= current_timestamp
 ;
 
+-- name: GetUserURLByID :one
+select
+  *
+from
+  urls
+where
+  id = $1
+  and
+  created_by = $2
+;
+
 -- name: DeleteURLFromId :exec
 delete from
   urls
@@ -67,3 +92,63 @@ where
   and
   created_by = $2
 ;
+
+-- name: GetRecentActiveURLs :many
+select
+  *
+from
+  urls
+where
+  expired_at is null
+  or
+  expired_at > current_timestamp
+order by
+  created_at desc
+limit $1
+;
+
+-- name: GetUserActiveURLByCanonicalURL :one
+select
+  *
+from
+  urls
+where
+  created_by = $1
+  and
+  canonical_url = $2
+  and
+  is_custom = false
+  and (
+    expired_at is null
+    or
+    expired_at > current_timestamp
+  )
+order by
+  created_at desc
+limit 1
+;
+
+-- name: QuarantineURL :exec
+update urls
+set
+  quarantined_at = current_timestamp,
+  quarantine_reason = $2
+where
+  id = $1
+;
+
+-- name: GetUserShortCodes :many
+select
+  short_code
+from
+  urls
+where
+  created_by = $1
+;
+
+-- name: DeleteUserURLs :exec
+delete from
+  urls
+where
+  created_by = $1
+;
@@ -0,0 +1,51 @@
+-- name: GetURLsExpiringSoon :many
+select
+  u.id,
+  u.short_code,
+  u.original_url,
+  u.expired_at,
+  o.user_id,
+  o.username,
+  o.email,
+  o.language,
+  o.timezone
+from
+  urls u
+  join users o on o.username = u.created_by
+where
+  u.expired_at > current_timestamp
+  and
+  u.expired_at <= sqlc.arg(expiring_before)::timestamp
+  and
+  u.expiry_reminded_at is null
+  and
+  u.quarantined_at is null
+  and
+  o.expiry_reminders = true
+order by
+  o.id,
+  u.expired_at
+limit sqlc.arg(max_urls)
+;
+
+-- name: MarkURLExpiryReminded :exec
+update urls
+set
+  expiry_reminded_at = current_timestamp
+where
+  id = $1
+;
+
+-- name: ExtendURLExpiration :one
+update urls
+set
+  expired_at = sqlc.narg(new_expired_at),
+  expiry_reminded_at = null
+where
+  id = sqlc.arg(id)
+  and
+  expired_at = sqlc.arg(expired_at)
+  and
+  expired_at > current_timestamp
+returning *
+;
@@ -0,0 +1,76 @@
+-- name: GetURLsDueForHealthCheck :many
+select
+  u.id,
+  u.original_url,
+  u.short_code,
+  u.created_by
+from
+  urls u
+  left join url_health_checks h on h.url_id = u.id
+where
+  (
+    u.expired_at is null
+    or
+    u.expired_at > current_timestamp
+  )
+  and
+  u.quarantined_at is null
+  and (
+    h.checked_at is null
+    or
+    h.checked_at < sqlc.arg(checked_before)::timestamp
+  )
+order by
+  h.checked_at nulls first
+limit sqlc.arg(max_urls)
+;
+
+-- name: UpsertURLHealthCheck :one
+insert into url_health_checks (
+  url_id,
+  status_code,
+  latency_ms,
+  redirect_chain,
+  error,
+  is_broken,
+  consecutive_failures,
+  checked_at
+) values (
+  sqlc.arg(url_id),
+  sqlc.arg(status_code),
+  sqlc.arg(latency_ms),
+  sqlc.arg(redirect_chain),
+  sqlc.arg(error),
+  sqlc.arg(is_broken),
+  case when sqlc.arg(is_broken)::boolean then 1 else 0 end,
+  current_timestamp
+)
+on conflict (url_id) do update
+set
+  status_code = excluded.status_code,
+  latency_ms = excluded.latency_ms,
+  redirect_chain = excluded.redirect_chain,
+  error = excluded.error,
+  is_broken = excluded.is_broken,
+  consecutive_failures = case when excluded.is_broken then url_health_checks.consecutive_failures + 1 else 0 end,
+  checked_at = excluded.checked_at,
+  notified_at = case when excluded.is_broken then url_health_checks.notified_at else null end
+returning *
+;
+
+-- name: MarkURLHealthNotified :exec
+update url_health_checks
+set
+  notified_at = current_timestamp
+where
+  url_id = $1
+;
+
+-- name: GetURLHealthChecksByURLIDs :many
+select
+  h.*
+from
+  url_health_checks h
+where
+  h.url_id = any(@url_ids::bigint[])
+;
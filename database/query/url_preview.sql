@@ -0,0 +1,36 @@
+-- name: UpsertURLPreview :exec
+insert into url_previews (
+  url_id,
+  title,
+  description,
+  og_title,
+  og_description,
+  og_image,
+  og_site_name,
+  favicon_url,
+  fetch_error,
+  fetched_at
+) values (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, current_timestamp
+)
+on conflict (url_id) do update
+set
+  title = excluded.title,
+  description = excluded.description,
+  og_title = excluded.og_title,
+  og_description = excluded.og_description,
+  og_image = excluded.og_image,
+  og_site_name = excluded.og_site_name,
+  favicon_url = excluded.favicon_url,
+  fetch_error = excluded.fetch_error,
+  fetched_at = excluded.fetched_at
+;
+
+-- name: GetURLPreviewsByURLIDs :many
+select
+  p.*
+from
+  url_previews p
+where
+  p.url_id = any(@url_ids::bigint[])
+;
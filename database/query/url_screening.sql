@@ -0,0 +1,35 @@
+-- name: GetURLsDueForScreening :many
+select
+  u.id,
+  u.short_code,
+  u.original_url,
+  u.redirect_rules,
+  u.ios_deep_link,
+  u.android_deep_link
+from
+  urls u
+where
+  (
+    u.expired_at is null
+    or
+    u.expired_at > current_timestamp
+  )
+  and
+  u.quarantined_at is null
+  and (
+    u.screened_at is null
+    or
+    u.screened_at < sqlc.arg(screened_before)::timestamp
+  )
+order by
+  u.screened_at nulls first
+limit sqlc.arg(max_urls)
+;
+
+-- name: MarkURLScreened :exec
+update urls
+set
+  screened_at = current_timestamp
+where
+  id = $1
+;
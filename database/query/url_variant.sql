@@ -0,0 +1,62 @@
+-- name: CreateURLVariant :one
+insert into url_variants (
+  url_id,
+  destination,
+  weight
+) values (
+  $1, $2, $3
+) returning *;
+
+-- name: GetURLVariants :many
+select
+  *
+from
+  url_variants
+where
+  url_id = $1
+order by
+  id
+;
+
+-- name: AddURLVariantClicks :exec
+update
+  url_variants
+set
+  clicks = clicks + $2
+where
+  id = $1
+;
+
+-- name: GetRecentActiveURLVariants :many
+select
+  v.*
+from
+  url_variants v
+where
+  v.url_id in (
+    select
+      id
+    from
+      urls
+    where
+      expired_at is null
+      or
+      expired_at > current_timestamp
+    order by
+      created_at desc
+    limit $1
+  )
+order by
+  v.url_id, v.id
+;
+
+-- name: GetURLVariantsByURLIDs :many
+select
+  v.*
+from
+  url_variants v
+where
+  v.url_id = any(@url_ids::bigint[])
+order by
+  v.url_id, v.id
+;
@@ -30,9 +30,10 @@ insert into users (
   user_id,
   username,
   password_hash,
-  email
+  email,
+  language
 ) values (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5
 );
 
 -- name: IsNewUserAvailable :one
@@ -52,4 +53,42 @@ set
   password_hash = $1
 where
   email = $2
-;
\ No newline at end of file
+;
+
+-- name: SetUserExpiryReminders :exec
+update users
+set
+  expiry_reminders = $2
+where
+  user_id = $1
+;
+
+-- name: UpdateUserEmail :exec
+update users
+set
+  email = $2
+where
+  user_id = $1
+;
+
+-- name: DeleteUser :exec
+delete from
+  users
+where
+  user_id = $1
+;
+
+-- name: UpdateUserProfile :one
+update users
+set
+  display_name = $2,
+  language = $3,
+  timezone = $4,
+  default_duration = $5,
+  default_redirect_type = $6,
+  reuse_existing_urls = $7,
+  expiry_reminders = $8
+where
+  user_id = $1
+returning *
+;
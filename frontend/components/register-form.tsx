@@ -25,7 +25,7 @@ export function RegisterForm() {
   const [isCodeSending, setIsCodeSending] = useState(false);
   const [countdown, setCountdown] = useState(0);
   const router = useRouter();
-  const { t } = useTranslation();
+  const { t, i18n } = useTranslation();
 
   const formSchema = z
     .object({
@@ -77,7 +77,7 @@ export function RegisterForm() {
         headers: {
           "Content-Type": "application/json"
         },
-        body: JSON.stringify(values)
+        body: JSON.stringify({ ...values, language: i18n.language })
       });
 
       const data = await response.json();
@@ -102,6 +102,20 @@ export function RegisterForm() {
     }
   }
 
+  // 重新发送前的倒计时
+  const startCountdown = (seconds: number) => {
+    setCountdown(seconds);
+    const timer = setInterval(() => {
+      setCountdown((prev) => {
+        if (prev <= 1) {
+          clearInterval(timer);
+          return 0;
+        }
+        return prev - 1;
+      });
+    }, 1000);
+  };
+
   // 发送邮箱验证码
   const sendEmailCode = async () => {
     const email = form.getValues("email");
@@ -124,7 +138,7 @@ export function RegisterForm() {
         headers: {
           "Content-Type": "application/json"
         },
-        body: JSON.stringify({ email })
+        body: JSON.stringify({ email, purpose: "register", language: i18n.language })
       });
 
       const data = await response.json();
@@ -133,17 +147,13 @@ export function RegisterForm() {
         toast.success(t("registerForm.sendCodeSuccess"));
 
         // 开始倒计时
-        setCountdown(60);
-        const timer = setInterval(() => {
-          setCountdown((prev) => {
-            if (prev <= 1) {
-              clearInterval(timer);
-              return 0;
-            }
-            return prev - 1;
-          });
-        }, 1000);
+        startCountdown(60);
       } else {
+        // 冷却中，按服务器返回的剩余时间倒计时
+        const retryAfter = Number(response.headers.get("Retry-After"));
+        if (response.status === 429 && retryAfter > 0) {
+          startCountdown(retryAfter);
+        }
         toast.error(t("registerForm.sendCodeError"), {
           description: data.message
         });
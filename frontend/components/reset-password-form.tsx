@@ -25,7 +25,7 @@ export function ResetPasswordForm() {
   const [isCodeSending, setIsCodeSending] = useState(false);
   const [countdown, setCountdown] = useState(0);
   const router = useRouter();
-  const { t } = useTranslation();
+  const { t, i18n } = useTranslation();
 
   const formSchema = z
     .object({
@@ -97,6 +97,20 @@ export function ResetPasswordForm() {
     }
   }
 
+  // 重新发送前的倒计时
+  const startCountdown = (seconds: number) => {
+    setCountdown(seconds);
+    const timer = setInterval(() => {
+      setCountdown((prev) => {
+        if (prev <= 1) {
+          clearInterval(timer);
+          return 0;
+        }
+        return prev - 1;
+      });
+    }, 1000);
+  };
+
   // 发送邮箱验证码
   const sendEmailCode = async () => {
     const email = form.getValues("email");
@@ -119,7 +133,7 @@ export function ResetPasswordForm() {
         headers: {
           "Content-Type": "application/json"
         },
-        body: JSON.stringify({ email })
+        body: JSON.stringify({ email, purpose: "reset_password", language: i18n.language })
       });
 
       const data = await response.json();
@@ -128,17 +142,13 @@ export function ResetPasswordForm() {
         toast.success(t("resetPasswordForm.sendCodeSuccess"));
 
         // 开始倒计时
-        setCountdown(60);
-        const timer = setInterval(() => {
-          setCountdown((prev) => {
-            if (prev <= 1) {
-              clearInterval(timer);
-              return 0;
-            }
-            return prev - 1;
-          });
-        }, 1000);
+        startCountdown(60);
       } else {
+        // 冷却中，按服务器返回的剩余时间倒计时
+        const retryAfter = Number(response.headers.get("Retry-After"));
+        if (response.status === 429 && retryAfter > 0) {
+          startCountdown(retryAfter);
+        }
         toast.error(t("resetPasswordForm.sendCodeError"), {
           description: data.message
         });
@@ -39,8 +39,8 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
         return;
       }
 
-      // 验证本地数据是否仍然有效（调用后端验证）
-      const response = await fetch("/api/user/test_auth", {
+      // 验证本地数据是否仍然有效，并从后端获取最新的用户资料
+      const response = await fetch("/api/user/me", {
         method: "GET",
         headers: {
           "Content-Type": "application/json"
@@ -48,9 +48,16 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       });
 
       if (response.ok) {
-        // 认证仍然有效，使用本地数据
+        // 认证仍然有效，使用最新的用户资料更新本地数据
+        const data = await response.json();
+        const userData: User = {
+          user_id: data.user_id,
+          username: data.username,
+          email: data.email
+        };
+        localStorage.setItem("user_data", JSON.stringify(userData));
         setIsAuthenticated(true);
-        setUser(JSON.parse(storedUserData));
+        setUser(userData);
       } else {
         // 认证已失效
         throw new Error("认证已失效");
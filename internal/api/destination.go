@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+)
+
+// buildDestination adds the UTM parameters of the short URL and the forwarded query string to the destination
+// The query string of the request wins over the UTM parameters only if the passthrough policy is override
+func buildDestination(urlInfo *model.LongURLInfo, query url.Values) (string, error) {
+	forwardQuery := urlInfo.QueryPassthrough != model.QueryPassthroughNone && len(query) > 0
+	if len(urlInfo.UTMParams) == 0 && !forwardQuery {
+		// Keep the destination exactly as it was given
+		return urlInfo.OriginalURL, nil
+	}
+
+	destination, err := url.Parse(urlInfo.OriginalURL)
+	if err != nil {
+		return "", err
+	}
+	existing := destination.Query()
+
+	// Only the added or replaced parameters are set, the rest of the query string is kept as given
+	merged := url.Values{}
+	for name, values := range urlInfo.UTMParams {
+		merged[name] = values
+	}
+	if forwardQuery {
+		for name, values := range query {
+			if urlInfo.QueryPassthrough == model.QueryPassthroughAppend && (existing.Has(name) || merged.Has(name)) {
+				continue
+			}
+			merged[name] = values
+		}
+	}
+	if len(merged) == 0 {
+		return urlInfo.OriginalURL, nil
+	}
+
+	destination.RawQuery = mergeRawQuery(destination.RawQuery, merged)
+	return destination.String(), nil
+}
+
+// mergeRawQuery drops the pairs of the merged parameters from the query string and appends their new values
+// The other pairs keep their order and encoding
+func mergeRawQuery(rawQuery string, merged url.Values) string {
+	var pairs []string
+	for pair := range strings.SplitSeq(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(pair, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil && merged.Has(decoded) {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	return strings.Join(append(pairs, merged.Encode()), "&")
+}
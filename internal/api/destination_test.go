@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+)
+
+func TestBuildDestination(t *testing.T) {
+	tests := []struct {
+		name        string
+		originalURL string
+		utmParams   url.Values
+		passthrough string
+		query       url.Values
+		want        string
+	}{
+		{
+			name:        "nothing merged keeps the destination",
+			originalURL: "https://example.com/a?b=1&a=%20x&flag",
+			passthrough: model.QueryPassthroughNone,
+			query:       url.Values{"q": {"1"}},
+			want:        "https://example.com/a?b=1&a=%20x&flag",
+		},
+		{
+			name:        "empty request query keeps the destination",
+			originalURL: "https://example.com/?z=1&a=2",
+			passthrough: model.QueryPassthroughAppend,
+			want:        "https://example.com/?z=1&a=2",
+		},
+		{
+			name:        "utm parameters are appended",
+			originalURL: "https://example.com/?z=1&a=%20x",
+			utmParams:   url.Values{"utm_source": {"news"}},
+			passthrough: model.QueryPassthroughNone,
+			want:        "https://example.com/?z=1&a=%20x&utm_source=news",
+		},
+		{
+			name:        "utm parameters replace the destination's",
+			originalURL: "https://example.com/?utm_source=old&z=1&utm_source=older",
+			utmParams:   url.Values{"utm_source": {"news"}},
+			passthrough: model.QueryPassthroughNone,
+			want:        "https://example.com/?z=1&utm_source=news",
+		},
+		{
+			name:        "append keeps existing parameters",
+			originalURL: "https://example.com/?id=1&flag",
+			passthrough: model.QueryPassthroughAppend,
+			query:       url.Values{"id": {"2"}, "ref": {"tw"}},
+			want:        "https://example.com/?id=1&flag&ref=tw",
+		},
+		{
+			name:        "append does not replace utm parameters",
+			originalURL: "https://example.com/",
+			utmParams:   url.Values{"utm_medium": {"email"}},
+			passthrough: model.QueryPassthroughAppend,
+			query:       url.Values{"utm_medium": {"social"}},
+			want:        "https://example.com/?utm_medium=email",
+		},
+		{
+			name:        "override replaces existing parameters",
+			originalURL: "https://example.com/?id=1&b=%2B",
+			passthrough: model.QueryPassthroughOverride,
+			query:       url.Values{"id": {"2"}},
+			want:        "https://example.com/?b=%2B&id=2",
+		},
+		{
+			name:        "fragment is kept",
+			originalURL: "https://example.com/p?x=1#top",
+			utmParams:   url.Values{"utm_term": {"a b"}},
+			passthrough: model.QueryPassthroughNone,
+			want:        "https://example.com/p?x=1&utm_term=a+b#top",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildDestination(&model.LongURLInfo{
+				OriginalURL:      tt.originalURL,
+				UTMParams:        tt.utmParams,
+				QueryPassthrough: tt.passthrough,
+			}, tt.query)
+			if err != nil {
+				t.Fatalf("buildDestination() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("buildDestination() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
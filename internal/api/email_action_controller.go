@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+// ExpiryReminder defines the interface for the one-click links in the expiry reminder emails
+type ExpiryReminder interface {
+	ExtendLink(ctx context.Context, token string) (*model.ExtendShortURLResponse, error)
+	Unsubscribe(ctx context.Context, token string) error
+}
+
+// emailActionPage is the data of the page showing the result of a link in an email
+type emailActionPage struct {
+	Title    string
+	Message  string
+	ShortURL string
+	Failed   bool
+	// Set on the confirmation page, the form posts the token back to the same address
+	Token  string
+	Button string
+}
+
+// EmailActionHandler handles the links in emails, which work without logging in
+type EmailActionHandler struct {
+	expiryReminder ExpiryReminder
+}
+
+// NewEmailActionHandler creates a new EmailActionHandler with the provided reminder
+func NewEmailActionHandler(expiryReminder ExpiryReminder) *EmailActionHandler {
+	return &EmailActionHandler{
+		expiryReminder: expiryReminder,
+	}
+}
+
+// GET /api/email/extend?token= (ask to confirm extending a link from the expiry reminder)
+// Mail scanners and link prefetchers follow the links in emails, so nothing changes until the form is posted
+func (h *EmailActionHandler) ConfirmExtendLink(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	return c.Render(http.StatusOK, "email_action.html", emailActionPage{
+		Title:   "Keep your short link?",
+		Message: "The short link will expire later, so that it keeps working.",
+		Token:   c.QueryParam("token"),
+		Button:  "Keep the short link",
+	})
+}
+
+// POST /api/email/extend token (extend a link from the expiry reminder)
+func (h *EmailActionHandler) ExtendLink(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	resp, err := h.expiryReminder.ExtendLink(c.Request().Context(), c.FormValue("token"))
+	switch {
+	case errors.Is(err, service.ErrInvalidEmailActionToken):
+		return c.Render(http.StatusBadRequest, "email_action.html", emailActionPage{
+			Title:   "This link does not work",
+			Message: "The link is invalid or has expired, please sign in to manage your short links.",
+			Failed:  true,
+		})
+	case errors.Is(err, service.ErrURLNotExtendable):
+		return c.Render(http.StatusConflict, "email_action.html", emailActionPage{
+			Title:   "The short link cannot be extended",
+			Message: "It was already extended, has expired, or was deleted.",
+			Failed:  true,
+		})
+	case err != nil:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	page := emailActionPage{
+		Title:    "Short link extended",
+		Message:  "The short link no longer expires.",
+		ShortURL: resp.ShortURL,
+	}
+	if resp.ExpiredAt != nil {
+		page.Message = "The short link now expires on " + resp.ExpiredAt.Format("2006-01-02 15:04 MST") + "."
+	}
+	return c.Render(http.StatusOK, "email_action.html", page)
+}
+
+// GET /api/email/unsubscribe?token= (ask to confirm stopping the expiry reminders)
+func (h *EmailActionHandler) ConfirmUnsubscribe(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	return c.Render(http.StatusOK, "email_action.html", emailActionPage{
+		Title:   "Unsubscribe?",
+		Message: "You will no longer be emailed about short links expiring soon.",
+		Token:   c.QueryParam("token"),
+		Button:  "Unsubscribe",
+	})
+}
+
+// POST /api/email/unsubscribe token (stop the expiry reminders)
+// Also the one-click unsubscribe of the List-Unsubscribe header, which posts to the address with the token in the query
+func (h *EmailActionHandler) Unsubscribe(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	err := h.expiryReminder.Unsubscribe(c.Request().Context(), c.FormValue("token"))
+	if errors.Is(err, service.ErrInvalidEmailActionToken) {
+		return c.Render(http.StatusBadRequest, "email_action.html", emailActionPage{
+			Title:   "This link does not work",
+			Message: "The link is invalid or has expired.",
+			Failed:  true,
+		})
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.Render(http.StatusOK, "email_action.html", emailActionPage{
+		Title:   "Unsubscribed",
+		Message: "You will no longer be emailed about short links expiring soon.",
+	})
+}
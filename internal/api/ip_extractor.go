@@ -0,0 +1,41 @@
+package api
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewIPExtractor gets the address of clients for the rate limits and the lockouts
+// Without trusted proxies the address of the connection is used, so clients cannot pick one with X-Forwarded-For
+// Otherwise X-Forwarded-For is read up to the first address outside of the trusted ranges
+func NewIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	// Only the configured ranges are trusted, not the loopback and private networks echo trusts by default
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			// A single address trusts only that address
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			bits := 8 * len(ip.To4())
+			if bits == 0 {
+				bits = 8 * net.IPv6len
+			}
+			ipRange = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimiter defines the interface for throttling requests
+type RateLimiter interface {
+	Allow(ctx context.Context, method string, path string, client model.RateLimitClient) (*model.RateLimitResult, error)
+}
+
+type UserIDExtractor interface {
+	ExtractUserIDFromJWT(ctx echo.Context) (string, error)
+}
+
+// NewRateLimitMiddleware throttles requests by IP address, user and API key
+// The API key is read from the header if it is not empty
+func NewRateLimitMiddleware(limiter RateLimiter, userIDExtractor UserIDExtractor, apiKeyHeader string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			client := model.RateLimitClient{
+				IP: c.RealIP(),
+			}
+			// Requests with a missing or invalid token are anonymous
+			if userID, err := userIDExtractor.ExtractUserIDFromJWT(c); err == nil {
+				client.UserID = userID
+			}
+			if apiKeyHeader != "" {
+				client.APIKey = c.Request().Header.Get(apiKeyHeader)
+			}
+
+			// The route pattern rather than the path, so every short code shares a policy
+			result, err := limiter.Allow(c.Request().Context(), c.Request().Method, c.Path(), client)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+			}
+			if result == nil {
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit, ceilSeconds(result.Window)))
+
+			if !result.Allowed {
+				header.Set(echo.HeaderRetryAfter, strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "too many requests, please try again later")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
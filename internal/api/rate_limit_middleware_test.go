@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+// fakeRateLimiter returns a fixed result, and records the client and route of the request
+type fakeRateLimiter struct {
+	result *model.RateLimitResult
+	err    error
+	route  string
+	client model.RateLimitClient
+}
+
+func (l *fakeRateLimiter) Allow(ctx context.Context, method string, path string, client model.RateLimitClient) (*model.RateLimitResult, error) {
+	l.route, l.client = method+" "+path, client
+	return l.result, l.err
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		result      *model.RateLimitResult
+		err         error
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{name: "not limited", wantStatus: http.StatusOK, wantHeaders: map[string]string{"RateLimit-Limit": ""}},
+		{
+			name:       "allowed",
+			result:     &model.RateLimitResult{Allowed: true, Limit: 60, Window: time.Minute, Remaining: 59, ResetAfter: 1500 * time.Millisecond},
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"RateLimit-Limit": "60", "RateLimit-Remaining": "59", "RateLimit-Reset": "2", "RateLimit-Policy": "60;w=60", "Retry-After": "",
+			},
+		},
+		{
+			name:       "denied",
+			result:     &model.RateLimitResult{Allowed: false, Limit: 60, Window: time.Minute, RetryAfter: 200 * time.Millisecond, ResetAfter: time.Minute},
+			wantStatus: http.StatusTooManyRequests,
+			wantHeaders: map[string]string{
+				"RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": "1",
+			},
+		},
+		{name: "limiter fails", err: errors.New("connection refused"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &fakeRateLimiter{result: tt.result, err: tt.err}
+			middleware := NewRateLimitMiddleware(limiter, fakeClaimsExtractor{userID: "user-1"}, "X-API-Key")
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+			req.Header.Set("X-API-Key", "some-key")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/:short_code")
+
+			err := middleware(func(c echo.Context) error { return c.NoContent(http.StatusOK) })(c)
+			status := rec.Code
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			}
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+
+			// Limits apply to the route pattern, not the path of each short code
+			if limiter.route != "GET /:short_code" {
+				t.Errorf("route = %q, want the route pattern", limiter.route)
+			}
+			if limiter.client.UserID != "user-1" || limiter.client.APIKey != "some-key" || limiter.client.IP == "" {
+				t.Errorf("client = %+v, want the user, API key and address of the request", limiter.client)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("header %s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
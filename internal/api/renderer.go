@@ -0,0 +1,32 @@
+package api
+
+import (
+	"embed"
+	"html/template"
+	"io"
+
+	"github.com/labstack/echo/v4"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// TemplateRenderer renders the embedded HTML pages
+// This is a implementation of the echo.Renderer interface
+type TemplateRenderer struct {
+	templates *template.Template
+}
+
+// NewTemplateRenderer parses the embedded HTML pages
+func NewTemplateRenderer() (*TemplateRenderer, error) {
+	templates, err := template.ParseFS(templateFS, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateRenderer{templates: templates}, nil
+}
+
+// Render executes the template with the given name
+func (r *TemplateRenderer) Render(w io.Writer, name string, data any, c echo.Context) error {
+	return r.templates.ExecuteTemplate(w, name, data)
+}
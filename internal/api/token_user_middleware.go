@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ZureTz/shorter-url/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+// TokenUserChecker checks that the user a token was issued to still exists
+type TokenUserChecker interface {
+	CheckTokenUser(ctx context.Context, userID string, username string) error
+}
+
+type TokenClaimsExtractor interface {
+	UserIDExtractor
+	JWTExtractor
+}
+
+// NewTokenUserMiddleware rejects the tokens of deleted accounts
+// Short URLs are owned by username, which a new account may take once the old one is deleted
+func NewTokenUserMiddleware(checker TokenUserChecker, extractor TokenClaimsExtractor) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID, err := extractor.ExtractUserIDFromJWT(c)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+			username, err := extractor.ExtractUsernameFromJWT(c)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+
+			err = checker.CheckTokenUser(c.Request().Context(), userID, username)
+			if errors.Is(err, service.ErrUserNotFound) {
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+			}
+
+			return next(c)
+		}
+	}
+}
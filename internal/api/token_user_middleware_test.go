@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ZureTz/shorter-url/internal/service"
+	"github.com/labstack/echo/v4"
+)
+
+// fakeClaimsExtractor returns fixed claims, or the error if set
+type fakeClaimsExtractor struct {
+	userID   string
+	username string
+	err      error
+}
+
+func (e fakeClaimsExtractor) ExtractUserIDFromJWT(c echo.Context) (string, error) {
+	return e.userID, e.err
+}
+
+func (e fakeClaimsExtractor) ExtractUsernameFromJWT(c echo.Context) (string, error) {
+	return e.username, e.err
+}
+
+// fakeUserChecker knows the usernames of the existing user IDs
+type fakeUserChecker map[string]string
+
+func (u fakeUserChecker) CheckTokenUser(ctx context.Context, userID string, username string) error {
+	if userID == "broken" {
+		return errors.New("database is down")
+	}
+	if existing, ok := u[userID]; !ok || existing != username {
+		return service.ErrUserNotFound
+	}
+	return nil
+}
+
+func TestTokenUserMiddleware(t *testing.T) {
+	users := fakeUserChecker{"new-id": "alice"}
+
+	tests := []struct {
+		name      string
+		extractor fakeClaimsExtractor
+		want      int
+	}{
+		{name: "existing user", extractor: fakeClaimsExtractor{userID: "new-id", username: "alice"}, want: http.StatusOK},
+		{name: "deleted user whose username was taken again", extractor: fakeClaimsExtractor{userID: "old-id", username: "alice"}, want: http.StatusUnauthorized},
+		{name: "username does not match", extractor: fakeClaimsExtractor{userID: "new-id", username: "bob"}, want: http.StatusUnauthorized},
+		{name: "invalid token", extractor: fakeClaimsExtractor{err: errors.New("no token")}, want: http.StatusUnauthorized},
+		{name: "lookup fails", extractor: fakeClaimsExtractor{userID: "broken", username: "alice"}, want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/user/me", nil), httptest.NewRecorder())
+			handler := NewTokenUserMiddleware(users, tt.extractor)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			err := handler(c)
+			got := c.Response().Status
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				got = httpErr.Code
+			} else if err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,34 +2,73 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"html/template"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/service"
 	"github.com/labstack/echo/v4"
 )
 
+// How long clients may cache permanent redirects
+const permanentRedirectMaxAge = 24 * time.Hour
+
+// How long the deep link page waits for the app to open before falling back to the destination
+const deepLinkFallbackDelay = 1500 * time.Millisecond
+
+// deepLinkPage is the data of the page opening the app
+type deepLinkPage struct {
+	AppURL          template.URL
+	FallbackURL     string
+	FallbackDelayMs int64
+}
+
+// How long clients may cache QR code images
+const qrCodeMaxAge = 24 * time.Hour
+
+// Cookie remembering the variant a visitor was sent to, followed by the short code
+const (
+	variantCookiePrefix = "ab_"
+	variantCookieMaxAge = 30 * 24 * time.Hour
+)
+
 // URLService defines the interface for URL-related operations
 type URLService interface {
 	CreateShortURL(ctx context.Context, req model.CreateShortURLRequest) (*model.CreateShortURLResponse, error)
-	GetLongURLInfo(ctx context.Context, shortURL string) (string, error)
+	GetLongURLInfo(ctx context.Context, shortURL string, visitor model.Visitor) (*model.LongURLInfo, error)
+	GetURLPreview(ctx context.Context, shortCode string) (*model.URLPreviewInfo, error)
 	GetMyURLs(ctx context.Context, req model.GetUserShortURLsRequest, username string) (*model.GetUserShortURLsResponse, error)
 	DeleteShortURL(ctx context.Context, req model.DeleteUserShortURLRequest, username string) (*model.DeleteUserShortURLResponse, error)
 }
 
+// QRCodeService defines the interface for rendering QR codes of short URLs
+type QRCodeService interface {
+	GetShortURLQRCode(ctx context.Context, shortCode string, req model.QRCodeRequest) (*model.QRCodeImage, error)
+	GetUserURLQRCode(ctx context.Context, req model.QRCodeRequest, username string) (*model.QRCodeImage, error)
+}
+
 type JWTExtractor interface {
+	ExtractUserIDFromJWT(ctx echo.Context) (string, error)
 	ExtractUsernameFromJWT(ctx echo.Context) (string, error)
 }
 
 type URLHandler struct {
-	urlService   URLService
-	jwtExtractor JWTExtractor
+	urlService    URLService
+	qrCodeService QRCodeService
+	jwtExtractor  JWTExtractor
 }
 
 // NewURLHandler creates a new URLHandler with the provided URLService
-func NewURLHandler(urlService URLService, jwtExtractor JWTExtractor) *URLHandler {
+func NewURLHandler(urlService URLService, qrCodeService QRCodeService, jwtExtractor JWTExtractor) *URLHandler {
 	return &URLHandler{
-		urlService:   urlService,
-		jwtExtractor: jwtExtractor,
+		urlService:    urlService,
+		qrCodeService: qrCodeService,
+		jwtExtractor:  jwtExtractor,
 	}
 }
 
@@ -40,14 +79,39 @@ func (h *URLHandler) CreateShortURL(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	req.IdempotencyKey = c.Request().Header.Get("Idempotency-Key")
 
 	// Validate the parameters (is it a valid URL, is custom_code valid, etc.)
 	if err := c.Validate(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
 	}
 
+	// The URL belongs to the user of the token
+	username, err := h.jwtExtractor.ExtractUsernameFromJWT(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+	req.CreatedBy = username
+
 	// Call the URL service to create the shortened URL
 	resp, err := h.urlService.CreateShortURL(c.Request().Context(), req)
+	// The idempotency key was used for another request
+	if errors.Is(err, service.ErrIdempotencyKeyReused) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	// The first request with the idempotency key has not finished yet
+	if errors.Is(err, service.ErrRequestInProgress) {
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	// The first request with the idempotency key failed, the client should send it again
+	if errors.Is(err, service.ErrIdempotentRequestFailed) {
+		c.Response().Header().Set(echo.HeaderRetryAfter, "1")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+	// The account was deleted while the token is still valid
+	if errors.Is(err, service.ErrUserNotFound) {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -61,14 +125,173 @@ func (h *URLHandler) RedirectToOriginalURL(c echo.Context) error {
 	// Get code from the URL path
 	shortcode := c.Param("short_code")
 
+	// A trailing "+" asks for the preview page instead of the redirect
+	if previewCode, ok := strings.CutSuffix(shortcode, "+"); ok {
+		return h.renderPreview(c, previewCode)
+	}
+	// A trailing ".qr" asks for the QR code of the short URL
+	if qrCode, ok := strings.CutSuffix(shortcode, ".qr"); ok {
+		return h.renderShortURLQRCode(c, qrCode)
+	}
+
 	// Get the original URL from the service using the code
-	originalURL, err := h.urlService.GetLongURLInfo(c.Request().Context(), shortcode)
+	visitor := model.Visitor{
+		IP:             c.RealIP(),
+		UserAgent:      c.Request().UserAgent(),
+		AcceptLanguage: c.Request().Header.Get("Accept-Language"),
+	}
+	// Visitors keep getting the same variant of split short URLs
+	if cookie, err := c.Cookie(variantCookiePrefix + shortcode); err == nil {
+		visitor.VariantID, _ = strconv.ParseInt(cookie.Value, 10, 64)
+	}
+
+	urlInfo, err := h.urlService.GetLongURLInfo(c.Request().Context(), shortcode, visitor)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, "URL not found")
 	}
 
-	// Redirect to the original URL
-	return c.Redirect(http.StatusFound, originalURL)
+	// Add the UTM parameters and the forwarded query string to the destination
+	destination, err := buildDestination(urlInfo, c.QueryParams())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	urlInfo.OriginalURL = destination
+
+	// Show a warning page instead of redirecting to flagged destinations, visitors may still continue at their own risk
+	if urlInfo.Quarantined {
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		return c.Render(http.StatusOK, "quarantine.html", urlInfo)
+	}
+
+	// Only visitors actually redirected keep their variant
+	if urlInfo.VariantID != 0 {
+		c.SetCookie(&http.Cookie{
+			Name:     variantCookiePrefix + shortcode,
+			Value:    strconv.FormatInt(urlInfo.VariantID, 10),
+			Path:     "/",
+			MaxAge:   int(variantCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	// Redirect to the original URL the way the owner chose
+	return h.redirect(c, urlInfo)
+}
+
+// redirect sends the client to the destination with the redirect type of the short URL
+func (h *URLHandler) redirect(c echo.Context, urlInfo *model.LongURLInfo) error {
+	// Try to open the app, the page falls back to the destination
+	if urlInfo.DeepLink != "" {
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		return c.Render(http.StatusOK, "deeplink.html", deepLinkPage{
+			// Deep links were validated when the short URL was created, their app schemes are safe
+			AppURL:          template.URL(urlInfo.DeepLink),
+			FallbackURL:     urlInfo.OriginalURL,
+			FallbackDelayMs: deepLinkFallbackDelay.Milliseconds(),
+		})
+	}
+
+	permanent := urlInfo.RedirectType == model.RedirectMovedPermanently || urlInfo.RedirectType == model.RedirectPermanent
+	switch {
+	case urlInfo.RedirectType == model.RedirectMetaRefresh:
+		// The page must be loaded every time so the analytics on it run
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		return c.Render(http.StatusOK, "redirect.html", urlInfo)
+	case permanent && !urlInfo.PerVisitor:
+		// Let clients cache permanent redirects, but not beyond the expiry of the short URL
+		maxAge := permanentRedirectMaxAge
+		if !urlInfo.ExpiredAt.IsZero() {
+			maxAge = min(maxAge, max(time.Until(urlInfo.ExpiredAt), 0))
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	default:
+		// Temporary redirects, and those depending on the visitor, go through the server every time
+		c.Response().Header().Set(echo.HeaderCacheControl, "private, no-cache")
+	}
+
+	code, err := strconv.Atoi(urlInfo.RedirectType)
+	if err != nil {
+		code = http.StatusFound
+	}
+	return c.Redirect(code, urlInfo.OriginalURL)
+}
+
+// GET /preview/:short_code (show the destination without redirecting)
+func (h *URLHandler) PreviewShortURL(c echo.Context) error {
+	return h.renderPreview(c, c.Param("short_code"))
+}
+
+// renderPreview responds with the preview page, or JSON if the client asks for it
+func (h *URLHandler) renderPreview(c echo.Context, shortcode string) error {
+	preview, err := h.urlService.GetURLPreview(c.Request().Context(), shortcode)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "URL not found")
+	}
+
+	// The response depends on the Accept header
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	if strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMEApplicationJSON) {
+		return c.JSON(http.StatusOK, preview)
+	}
+	return c.Render(http.StatusOK, "preview.html", preview)
+}
+
+// renderShortURLQRCode responds with the QR code of any short URL
+func (h *URLHandler) renderShortURLQRCode(c echo.Context, shortcode string) error {
+	var req model.QRCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	image, err := h.qrCodeService.GetShortURLQRCode(c.Request().Context(), shortcode, req)
+	if err != nil {
+		return qrCodeError(err)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(qrCodeMaxAge.Seconds())))
+	return c.Blob(http.StatusOK, image.ContentType, image.Data)
+}
+
+// GET /api/user/url/:id/qr
+func (h *URLHandler) GetMyURLQRCode(c echo.Context) error {
+	// Extract parameters from the request
+	var req model.QRCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// Get user ID from JWT
+	username, err := h.jwtExtractor.ExtractUsernameFromJWT(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	image, err := h.qrCodeService.GetUserURLQRCode(c.Request().Context(), req, username)
+	if err != nil {
+		return qrCodeError(err)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int(qrCodeMaxAge.Seconds())))
+	return c.Blob(http.StatusOK, image.ContentType, image.Data)
+}
+
+// qrCodeError maps the errors of the QR code service to HTTP errors
+func qrCodeError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrURLNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "URL not found")
+	case errors.Is(err, service.ErrInvalidQRCodeOptions):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
 }
 
 // GET /api/user/my_urls
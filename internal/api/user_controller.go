@@ -2,10 +2,14 @@ package api
 
 import (
 	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/service"
 	"github.com/labstack/echo/v4"
 )
 
@@ -14,15 +18,22 @@ type UserService interface {
 	UserRegister(ctx context.Context, req model.RegisterRequest) error
 	GetEmailCode(ctx context.Context, req model.GetEmailCodeRequest) error
 	ResetPassword(ctx context.Context, req model.ResetPasswordRequest) error
+	RequestEmailChange(ctx context.Context, req model.RequestEmailChangeRequest) error
+	ChangeEmail(ctx context.Context, req model.ChangeEmailRequest) error
+	DeleteAccount(ctx context.Context, req model.DeleteAccountRequest) error
+	GetProfile(ctx context.Context, userID string) (*model.UserProfile, error)
+	UpdateProfile(ctx context.Context, req model.UpdateProfileRequest) (*model.UserProfile, error)
 }
 
 type UserHandler struct {
-	userService UserService
+	userService  UserService
+	jwtExtractor JWTExtractor
 }
 
-func NewUserHandler(userService UserService) *UserHandler {
+func NewUserHandler(userService UserService, jwtExtractor JWTExtractor) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:  userService,
+		jwtExtractor: jwtExtractor,
 	}
 }
 
@@ -33,6 +44,7 @@ func (h *UserHandler) UserLogin(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	req.ClientIP = c.RealIP()
 
 	// Validate the parameters (username and password)
 	if err := c.Validate(&req); err != nil {
@@ -43,6 +55,9 @@ func (h *UserHandler) UserLogin(c echo.Context) error {
 	resp, err := h.userService.UserLogin(c.Request().Context(), req)
 	// User does not exist or password is incorrect or any other error
 	if err != nil {
+		if lockedOut := new(service.LockedOutError); errors.As(err, &lockedOut) {
+			return tooManyRequests(c, lockedOut.RetryAfter, lockedOut)
+		}
 		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
 	}
 
@@ -65,6 +80,11 @@ func (h *UserHandler) UserRegister(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	req.ClientIP = c.RealIP()
+	// Fall back to the languages of the browser
+	if req.Language == "" {
+		req.Language = c.Request().Header.Get("Accept-Language")
+	}
 
 	// Validate the parameters (username, password, email, and email code)
 	if err := c.Validate(&req); err != nil {
@@ -73,6 +93,9 @@ func (h *UserHandler) UserRegister(c echo.Context) error {
 
 	// Call the user service to register
 	if err := h.userService.UserRegister(c.Request().Context(), req); err != nil {
+		if lockedOut := new(service.LockedOutError); errors.As(err, &lockedOut) {
+			return tooManyRequests(c, lockedOut.RetryAfter, lockedOut)
+		}
 		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
 	}
 
@@ -89,6 +112,7 @@ func (h *UserHandler) ResetPassword(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	req.ClientIP = c.RealIP()
 
 	// Validate the parameters (email, email code, password, and confirmed password)
 	if err := c.Validate(&req); err != nil {
@@ -97,6 +121,9 @@ func (h *UserHandler) ResetPassword(c echo.Context) error {
 
 	// Call the user service to reset the password
 	if err := h.userService.ResetPassword(c.Request().Context(), req); err != nil {
+		if lockedOut := new(service.LockedOutError); errors.As(err, &lockedOut) {
+			return tooManyRequests(c, lockedOut.RetryAfter, lockedOut)
+		}
 		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
 	}
 
@@ -113,6 +140,10 @@ func (h *UserHandler) GetEmailCode(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
+	// Fall back to the languages of the browser
+	if req.Language == "" {
+		req.Language = c.Request().Header.Get("Accept-Language")
+	}
 
 	// Validate the email parameter
 	if err := c.Validate(&req); err != nil {
@@ -122,6 +153,9 @@ func (h *UserHandler) GetEmailCode(c echo.Context) error {
 	// Call the user service to get the email code
 	err := h.userService.GetEmailCode(c.Request().Context(), req)
 	if err != nil {
+		if cooldown := new(service.EmailCodeCooldownError); errors.As(err, &cooldown) {
+			return tooManyRequests(c, cooldown.RetryAfter, cooldown)
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
 
@@ -131,6 +165,184 @@ func (h *UserHandler) GetEmailCode(c echo.Context) error {
 	})
 }
 
+// POST /api/user/email_code (send the codes for changing the email)
+func (h *UserHandler) RequestEmailChange(c echo.Context) error {
+	// Extract the new email from the request
+	var req model.RequestEmailChangeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// Validate the new email
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	userID, err := h.jwtExtractor.ExtractUserIDFromJWT(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+	req.UserID = userID
+
+	// Call the user service to send the codes to the current and the new email
+	err = h.userService.RequestEmailChange(c.Request().Context(), req)
+	if err != nil {
+		if cooldown := new(service.EmailCodeCooldownError); errors.As(err, &cooldown) {
+			return tooManyRequests(c, cooldown.RetryAfter, cooldown)
+		}
+		if errors.Is(err, service.ErrUserNotFound) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		if errors.Is(err, service.ErrEmailTaken) || errors.Is(err, service.ErrEmailUnchanged) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, map[string]string{
+		"message": "Email codes sent successfully",
+	})
+}
+
+// PUT /api/user/email
+func (h *UserHandler) ChangeEmail(c echo.Context) error {
+	// Extract parameters from the request
+	var req model.ChangeEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	req.ClientIP = c.RealIP()
+
+	// Validate the parameters (new email and the codes of both emails)
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	userID, err := h.jwtExtractor.ExtractUserIDFromJWT(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+	req.UserID = userID
+
+	// Call the user service to change the email
+	if err := h.userService.ChangeEmail(c.Request().Context(), req); err != nil {
+		if lockedOut := new(service.LockedOutError); errors.As(err, &lockedOut) {
+			return tooManyRequests(c, lockedOut.RetryAfter, lockedOut)
+		}
+		if errors.Is(err, service.ErrEmailTaken) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrInvalidEmailCode) || errors.Is(err, service.ErrTooManyCodeAttempts) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Email changed successfully",
+	})
+}
+
+// GET /api/user/me
+func (h *UserHandler) GetProfile(c echo.Context) error {
+	userID, err := h.jwtExtractor.ExtractUserIDFromJWT(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+
+	// Call the user service to get the profile
+	profile, err := h.userService.GetProfile(c.Request().Context(), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, profile)
+}
+
+// PUT /api/user/me display_name, language, timezone, default_duration, default_redirect_type, ...
+func (h *UserHandler) UpdateProfile(c echo.Context) error {
+	// Extract parameters from the request
+	var req model.UpdateProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// Validate the parameters (lengths, time zone and redirect type)
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	userID, err := h.jwtExtractor.ExtractUserIDFromJWT(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+	req.UserID = userID
+
+	// Call the user service to update the profile
+	profile, err := h.userService.UpdateProfile(c.Request().Context(), req)
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, profile)
+}
+
+// DELETE /api/user/me password, delete_urls
+func (h *UserHandler) DeleteAccount(c echo.Context) error {
+	// Extract parameters from the request
+	var req model.DeleteAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	req.ClientIP = c.RealIP()
+
+	// Validate the parameters (password)
+	if err := c.Validate(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	userID, err := h.jwtExtractor.ExtractUserIDFromJWT(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+	req.UserID = userID
+
+	// Call the user service to delete the account
+	if err := h.userService.DeleteAccount(c.Request().Context(), req); err != nil {
+		if lockedOut := new(service.LockedOutError); errors.As(err, &lockedOut) {
+			return tooManyRequests(c, lockedOut.RetryAfter, lockedOut)
+		}
+		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrIncorrectPassword) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// Log out by removing the cookie with the JWT token
+	c.SetCookie(&http.Cookie{
+		Name:     "token",
+		Value:    "",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Account deleted successfully",
+	})
+}
+
+// tooManyRequests responds to requests refused by a lockout or a cooldown
+func tooManyRequests(c echo.Context, retryAfter time.Duration, err error) error {
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(max(int(math.Ceil(retryAfter.Seconds())), 1)))
+	return echo.NewHTTPError(http.StatusTooManyRequests, err.Error())
+}
+
 // GET /api/user/test_auth
 func (h *UserHandler) TestAuth(c echo.Context) error {
 	// This endpoint is for testing user authentication
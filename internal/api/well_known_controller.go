@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/labstack/echo/v4"
+)
+
+// Paths of the server that never open the app
+var appleExcludedPaths = []string{"/api/*", "/preview/*"}
+
+// WellKnownHandler serves the files that let mobile apps open short links
+type WellKnownHandler struct {
+	appleAppSiteAssociation []byte
+	androidAssetLinks       []byte
+}
+
+// NewWellKnownHandler builds the files from the config
+// A file is not served if its platform is not configured
+func NewWellKnownHandler(c config.DeepLinkConfig) (*WellKnownHandler, error) {
+	h := &WellKnownHandler{}
+
+	if len(c.AppleAppIDs) > 0 {
+		// Claim every short URL unless paths are configured
+		components := []map[string]any{}
+		if len(c.ApplePaths) == 0 {
+			for _, path := range appleExcludedPaths {
+				components = append(components, map[string]any{"/": path, "exclude": true})
+			}
+			components = append(components, map[string]any{"/": "/*"})
+		}
+		for _, path := range c.ApplePaths {
+			components = append(components, map[string]any{"/": path})
+		}
+
+		association, err := json.Marshal(map[string]any{
+			"applinks": map[string]any{
+				"details": []map[string]any{{
+					"appIDs":     c.AppleAppIDs,
+					"components": components,
+				}},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		h.appleAppSiteAssociation = association
+	}
+
+	if c.AndroidPackageName != "" {
+		assetLinks, err := json.Marshal([]map[string]any{{
+			"relation": []string{"delegate_permission/common.handle_all_urls"},
+			"target": map[string]any{
+				"namespace":                "android_app",
+				"package_name":             c.AndroidPackageName,
+				"sha256_cert_fingerprints": c.AndroidCertFingerprints,
+			},
+		}})
+		if err != nil {
+			return nil, err
+		}
+		h.androidAssetLinks = assetLinks
+	}
+
+	return h, nil
+}
+
+// GET /.well-known/apple-app-site-association
+func (h *WellKnownHandler) AppleAppSiteAssociation(c echo.Context) error {
+	if h.appleAppSiteAssociation == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Not found")
+	}
+	return c.JSONBlob(http.StatusOK, h.appleAppSiteAssociation)
+}
+
+// GET /.well-known/assetlinks.json
+func (h *WellKnownHandler) AndroidAssetLinks(c echo.Context) error {
+	if h.androidAssetLinks == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Not found")
+	}
+	return c.JSONBlob(http.StatusOK, h.androidAssetLinks)
+}
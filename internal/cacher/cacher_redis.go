@@ -9,9 +9,12 @@ import (
 )
 
 type RedisCacher struct {
-	client               *redis.Client
-	uRLAverageExpiration time.Duration
-	emailCodeExpiration  time.Duration
+	client                   *redis.Client
+	uRLAverageExpiration     time.Duration
+	emailCodeExpiration      time.Duration
+	emailCodeCooldown        time.Duration
+	idempotencyKeyExpiration time.Duration
+	qrCodeExpiration         time.Duration
 }
 
 // NewRedisCacher creates a new Cacher instance with the provided Redis client
@@ -28,11 +31,19 @@ func NewRedisCacher(c config.CacherConfig) (*RedisCacher, error) {
 		return nil, err
 	}
 
+	idempotencyKeyExpiration := c.IdempotencyKeyExpiration
+	if idempotencyKeyExpiration <= 0 {
+		idempotencyKeyExpiration = idempotencyDefaultExpiration
+	}
+
 	// If successful, return the RedisCacher instance
 	return &RedisCacher{
-		client:               client,
-		uRLAverageExpiration: c.URLAverageExpiration,
-		emailCodeExpiration:  c.EmailCodeExpiration,
+		client:                   client,
+		uRLAverageExpiration:     c.URLAverageExpiration,
+		emailCodeExpiration:      c.EmailCodeExpiration,
+		emailCodeCooldown:        c.EmailCodeCooldown,
+		idempotencyKeyExpiration: idempotencyKeyExpiration,
+		qrCodeExpiration:         c.QRCodeExpiration,
 	}, nil
 }
 
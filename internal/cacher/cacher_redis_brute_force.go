@@ -0,0 +1,53 @@
+package cacher
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	failedAttemptsKeyPrefix = "failures:"
+	lockoutKeyPrefix        = "lockout:"
+)
+
+// incrFailedAttemptsScript counts a failure and starts the window with the first one
+// Both happen in the script, so a count can never be left without an expiration
+var incrFailedAttemptsScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// IncrFailedAttempts counts a failed attempt, the count is dropped once the window since the first one has passed
+// Returns the number of failed attempts in the window
+func (c *RedisCacher) IncrFailedAttempts(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return incrFailedAttemptsScript.Run(ctx, c.client, []string{failedAttemptsKeyPrefix + key}, window.Milliseconds()).Int64()
+}
+
+// ClearFailedAttempts resets the count of failed attempts
+func (c *RedisCacher) ClearFailedAttempts(ctx context.Context, key string) error {
+	return c.client.Del(ctx, failedAttemptsKeyPrefix+key).Err()
+}
+
+// LockOut locks the key for the duration
+func (c *RedisCacher) LockOut(ctx context.Context, key string, duration time.Duration) error {
+	return c.client.Set(ctx, lockoutKeyPrefix+key, time.Now().Add(duration).Unix(), duration).Err()
+}
+
+// GetLockout gets how long the key stays locked, or 0 if it is not locked
+func (c *RedisCacher) GetLockout(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.client.PTTL(ctx, lockoutKeyPrefix+key).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Negative values mean the key does not exist or has no expiration
+	return max(ttl, 0), nil
+}
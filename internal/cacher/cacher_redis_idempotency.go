@@ -0,0 +1,51 @@
+package cacher
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyKeyPrefix = "idempotency:"
+
+// How long a request holding an idempotency key may take before another one can retry it
+const idempotencyLockExpiration = time.Minute
+
+// Used if idempotency_key_expiration is not set, records stored without an expiration would never be deleted
+const idempotencyDefaultExpiration = 24 * time.Hour
+
+// GetIdempotencyRecord gets the record stored for the idempotency key, or nil if there is none
+func (c *RedisCacher) GetIdempotencyRecord(ctx context.Context, key string) ([]byte, error) {
+	record, err := c.client.Get(ctx, idempotencyKeyPrefix+key).Bytes()
+	// No request with this key has been seen
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ReserveIdempotencyKey stores the record only if no request holds the key yet
+// Returns false if the key is already taken
+func (c *RedisCacher) ReserveIdempotencyKey(ctx context.Context, key string, record []byte) (bool, error) {
+	return c.client.SetNX(ctx, idempotencyKeyPrefix+key, record, idempotencyLockExpiration).Result()
+}
+
+// StoreIdempotencyRecord stores the final record of the request for the idempotency window
+func (c *RedisCacher) StoreIdempotencyRecord(ctx context.Context, key string, record []byte) error {
+	return c.client.Set(ctx, idempotencyKeyPrefix+key, record, c.idempotencyKeyExpiration).Err()
+}
+
+// DeleteIdempotencyRecord releases the idempotency key so that the request can be retried
+func (c *RedisCacher) DeleteIdempotencyRecord(ctx context.Context, key string) error {
+	err := c.client.Del(ctx, idempotencyKeyPrefix+key).Err()
+	// If the key does not exist, consider it successful
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package cacher
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const qrCodeKeyPrefix = "qr:"
+
+// GetQRCode gets the rendered QR code image, or nil if it is not cached
+func (c *RedisCacher) GetQRCode(ctx context.Context, key string) ([]byte, error) {
+	image, err := c.client.Get(ctx, qrCodeKeyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// StoreQRCode stores the rendered QR code image
+func (c *RedisCacher) StoreQRCode(ctx context.Context, key string, image []byte) error {
+	return c.client.Set(ctx, qrCodeKeyPrefix+key, image, c.qrCodeExpiration).Err()
+}
@@ -0,0 +1,63 @@
+package cacher
+
+import (
+	"context"
+	"time"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = "ratelimit:"
+
+// takeTokenScript takes a token from a bucket holding up to capacity tokens, refilled evenly over the window
+// Times come from the redis server, so every instance of the app shares the same clock
+// Returns whether a token was taken, the tokens left, and the milliseconds until the next token and until the bucket is full
+var takeTokenScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+
+local rate = capacity / window
+tokens = math.min(capacity, tokens + math.max(now - ts, 0) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], window)
+
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+// TakeRateLimitToken takes a token from the bucket of the key, holding up to limit tokens refilled over the window
+func (c *RedisCacher) TakeRateLimitToken(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error) {
+	result, err := takeTokenScript.Run(ctx, c.client, []string{rateLimitKeyPrefix + key}, limit, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.RateLimitResult{
+		Allowed:    result[0] == 1,
+		Limit:      limit,
+		Window:     window,
+		Remaining:  int(result[1]),
+		RetryAfter: time.Duration(result[2]) * time.Millisecond,
+		ResetAfter: time.Duration(result[3]) * time.Millisecond,
+	}, nil
+}
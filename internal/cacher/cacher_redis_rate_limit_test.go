@@ -0,0 +1,113 @@
+package cacher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+// newTestCacher connects to the redis server at REDIS_ADDR, the test is skipped if it is not set
+// The tests only write keys with a random suffix, which expire on their own
+func newTestCacher(t *testing.T) *RedisCacher {
+	t.Helper()
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	c, err := NewRedisCacher(config.CacherConfig{CacherURL: addr})
+	if err != nil {
+		t.Fatalf("failed to connect to redis at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func testKey(t *testing.T) string {
+	return fmt.Sprintf("test:%s:%d", t.Name(), time.Now().UnixNano())
+}
+
+func TestTakeRateLimitTokenBurstAndRefill(t *testing.T) {
+	c := newTestCacher(t)
+	ctx := context.Background()
+	key := testKey(t)
+	window := time.Second
+
+	// The bucket starts full, so the whole budget can be spent at once
+	for want := 2; want >= 0; want-- {
+		result, err := c.TakeRateLimitToken(ctx, key, 3, window)
+		if err != nil {
+			t.Fatalf("TakeRateLimitToken() error = %v", err)
+		}
+		if !result.Allowed || result.Remaining != want {
+			t.Fatalf("TakeRateLimitToken() = %+v, want allowed with %d remaining", result, want)
+		}
+		if result.Limit != 3 || result.Window != window {
+			t.Errorf("Limit = %d, Window = %v, want 3 and %v", result.Limit, result.Window, window)
+		}
+	}
+
+	result, err := c.TakeRateLimitToken(ctx, key, 3, window)
+	if err != nil {
+		t.Fatalf("TakeRateLimitToken() error = %v", err)
+	}
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("TakeRateLimitToken() = %+v, want denied", result)
+	}
+	// One token comes back every window/limit
+	if result.RetryAfter <= 0 || result.RetryAfter > window/3+time.Millisecond {
+		t.Errorf("RetryAfter = %v, want at most %v", result.RetryAfter, window/3)
+	}
+	if result.ResetAfter < result.RetryAfter || result.ResetAfter > window {
+		t.Errorf("ResetAfter = %v, want between RetryAfter and the window", result.ResetAfter)
+	}
+
+	// Denied requests do not take a token, so the next one is allowed after RetryAfter
+	time.Sleep(result.RetryAfter + 10*time.Millisecond)
+	result, err = c.TakeRateLimitToken(ctx, key, 3, window)
+	if err != nil {
+		t.Fatalf("TakeRateLimitToken() error = %v", err)
+	}
+	if !result.Allowed {
+		t.Errorf("TakeRateLimitToken() = %+v, want allowed after RetryAfter", result)
+	}
+
+	// The bucket is full again after the window, and never holds more than the limit
+	time.Sleep(window + 50*time.Millisecond)
+	result, err = c.TakeRateLimitToken(ctx, key, 3, window)
+	if err != nil {
+		t.Fatalf("TakeRateLimitToken() error = %v", err)
+	}
+	if !result.Allowed || result.Remaining != 2 {
+		t.Errorf("TakeRateLimitToken() = %+v, want allowed with 2 remaining", result)
+	}
+}
+
+func TestTakeRateLimitTokenKeys(t *testing.T) {
+	c := newTestCacher(t)
+	ctx := context.Background()
+	key, otherKey := testKey(t), testKey(t)+":other"
+
+	if _, err := c.TakeRateLimitToken(ctx, key, 1, time.Minute); err != nil {
+		t.Fatalf("TakeRateLimitToken() error = %v", err)
+	}
+	if result, err := c.TakeRateLimitToken(ctx, key, 1, time.Minute); err != nil || result.Allowed {
+		t.Fatalf("TakeRateLimitToken() = %+v, %v, want denied", result, err)
+	}
+	// Buckets of other keys are separate
+	if result, err := c.TakeRateLimitToken(ctx, otherKey, 1, time.Minute); err != nil || !result.Allowed {
+		t.Fatalf("TakeRateLimitToken() of another key = %+v, %v, want allowed", result, err)
+	}
+
+	// Idle buckets expire after the window, when they would be full again anyway
+	ttl, err := c.client.PTTL(ctx, rateLimitKeyPrefix+key).Result()
+	if err != nil {
+		t.Fatalf("PTTL() error = %v", err)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("PTTL() = %v, want at most the window", ttl)
+	}
+}
@@ -7,14 +7,35 @@ import (
 	// "log"
 	"time"
 
-	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/internal/model"
 	"github.com/redis/go-redis/v9"
 )
 
 const urlKeyPrefix = "url:"
 
+// Sorted set ranking short codes by their number of redirects
+const urlHitsKey = "urlHits"
+
 // StoreURLToCache stores the URL information in the cache using redis
-func (c *RedisCacher) StoreURLToCache(ctx context.Context, urlInfo repo.Url) error {
+func (c *RedisCacher) StoreURLToCache(ctx context.Context, urlInfo model.CachedURL) error {
+	return c.storeURLToCache(ctx, c.client, urlInfo)
+}
+
+// StoreURLsToCache stores multiple URLs in the cache using a single redis pipeline
+func (c *RedisCacher) StoreURLsToCache(ctx context.Context, urlInfos []model.CachedURL) error {
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, urlInfo := range urlInfos {
+			if err := c.storeURLToCache(ctx, pipe, urlInfo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// storeURLToCache queues or executes the SET command for a single URL on the given client or pipeline
+func (c *RedisCacher) storeURLToCache(ctx context.Context, cmd redis.Cmdable, urlInfo model.CachedURL) error {
 	// Stringify the URL information
 	stringifiedURLInfo, err := json.Marshal(urlInfo)
 	if err != nil {
@@ -28,12 +49,17 @@ func (c *RedisCacher) StoreURLToCache(ctx context.Context, urlInfo repo.Url) err
 		expirationDuration = min(expirationDuration, urlInfo.ExpiredAt.Time.Sub(time.Now().UTC()))
 	}
 
+	// Already expired URLs are not worth caching
+	if expirationDuration <= 0 {
+		return nil
+	}
+
 	// Log the expiration duration for debugging purposes
 	// log.Println("Average expiration duration: ", c.averageExpiration)
 	// log.Println("Setting expiration duration for URL", urlInfo.ShortCode, ":", expirationDuration)
 
 	// Set the URL information in Redis with an expiration time
-	err = c.client.Set(ctx, urlKeyPrefix+urlInfo.ShortCode, stringifiedURLInfo, expirationDuration).Err()
+	err = cmd.Set(ctx, urlKeyPrefix+urlInfo.ShortCode, stringifiedURLInfo, expirationDuration).Err()
 	if err != nil {
 		return err
 	}
@@ -43,7 +69,7 @@ func (c *RedisCacher) StoreURLToCache(ctx context.Context, urlInfo repo.Url) err
 }
 
 // GetURLFromCache retrieves the URL information from the cache using redis
-func (c *RedisCacher) GetURLFromCache(ctx context.Context, shortCode string) (*repo.Url, error) {
+func (c *RedisCacher) GetURLFromCache(ctx context.Context, shortCode string) (*model.CachedURL, error) {
 	// Get the URL information from Redis
 	stringifiedURLInfo, err := c.client.Get(ctx, urlKeyPrefix+shortCode).Bytes()
 	// If the key does not exist, return nil
@@ -56,7 +82,7 @@ func (c *RedisCacher) GetURLFromCache(ctx context.Context, shortCode string) (*r
 	}
 
 	// The URL information was found, unmarshal it
-	var urlInfo repo.Url
+	var urlInfo model.CachedURL
 	err = json.Unmarshal([]byte(stringifiedURLInfo), &urlInfo)
 	if err != nil {
 		return nil, err
@@ -79,3 +105,35 @@ func (c *RedisCacher) DeleteURLFromCache(ctx context.Context, shortCode string)
 	// If there was an error, return it
 	return err
 }
+
+// IncrURLHits increases the click counter of the short code in the hot key ranking
+func (c *RedisCacher) IncrURLHits(ctx context.Context, shortCode string) error {
+	return c.client.ZIncrBy(ctx, urlHitsKey, 1, shortCode).Err()
+}
+
+// GetHotShortCodes returns up to limit short codes with the most clicks, most clicked first
+func (c *RedisCacher) GetHotShortCodes(ctx context.Context, limit int64) ([]string, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	return c.client.ZRevRange(ctx, urlHitsKey, 0, limit-1).Result()
+}
+
+// TrimHotShortCodes keeps only the top keep short codes in the hot key ranking
+func (c *RedisCacher) TrimHotShortCodes(ctx context.Context, keep int64) error {
+	// Ranks are in ascending order, so remove everything below the top `keep` entries
+	return c.client.ZRemRangeByRank(ctx, urlHitsKey, 0, -keep-1).Err()
+}
+
+// RemoveHotShortCodes removes the short codes from the hot key ranking
+func (c *RedisCacher) RemoveHotShortCodes(ctx context.Context, shortCodes ...string) error {
+	if len(shortCodes) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(shortCodes))
+	for i, shortCode := range shortCodes {
+		members[i] = shortCode
+	}
+	return c.client.ZRem(ctx, urlHitsKey, members...).Err()
+}
@@ -2,16 +2,31 @@ package cacher
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-const emailKeyPrefix = "emailCode:"
+// Email codes are keyed by purpose and email, so that each email has one active code per purpose
+const (
+	emailKeyPrefix         = "emailCode:"
+	emailCooldownKeyPrefix = "emailCodeCooldown:"
+)
+
+// Emails are case-insensitive in practice, so differently cased spellings share the code and the cooldown
+func emailCodeKey(purpose string, email string) string {
+	return emailKeyPrefix + purpose + ":" + strings.ToLower(email)
+}
+
+func emailCooldownKey(email string) string {
+	return emailCooldownKeyPrefix + strings.ToLower(email)
+}
 
-// Get email using the email code from Redis
-func (c *RedisCacher) GetEmailUsingCode(ctx context.Context, emailCode string) (*string, error) {
-	email, err := c.client.Get(ctx, emailKeyPrefix+emailCode).Result()
-	// Email code not found
+// GetEmailCode gets the active code sent to the email for the purpose, or nil if there is none
+func (c *RedisCacher) GetEmailCode(ctx context.Context, purpose string, email string) (*string, error) {
+	emailCode, err := c.client.Get(ctx, emailCodeKey(purpose, email)).Result()
+	// No code was sent, or it expired
 	if err == redis.Nil {
 		return nil, nil
 	}
@@ -20,33 +35,43 @@ func (c *RedisCacher) GetEmailUsingCode(ctx context.Context, emailCode string) (
 		return nil, err
 	}
 
-	// Email code found, return the email
-	return &email, nil
+	// Email code found, return it
+	return &emailCode, nil
 }
 
-func (c *RedisCacher) StoreCodeAndEmail(ctx context.Context, emailCode string, email string) error {
-	// Store the email code and email in Redis with an expiration time
-	err := c.client.Set(ctx, emailKeyPrefix+emailCode, email, c.emailCodeExpiration).Err()
-	if err != nil {
-		return err
-	}
-
-	// Successfully stored the email code and email in cache, return nil
-	return nil
+// StoreEmailCode stores the code sent to the email for the purpose, replacing the previous one
+func (c *RedisCacher) StoreEmailCode(ctx context.Context, purpose string, email string, emailCode string) error {
+	return c.client.Set(ctx, emailCodeKey(purpose, email), emailCode, c.emailCodeExpiration).Err()
 }
 
-// DeleteEmailCode deletes the email code from Redis
-func (c *RedisCacher) DeleteEmailCode(ctx context.Context, emailCode string) error {
-	err := c.client.Del(ctx, emailKeyPrefix+emailCode).Err()
+// DeleteEmailCode deletes the code sent to the email for the purpose
+func (c *RedisCacher) DeleteEmailCode(ctx context.Context, purpose string, email string) error {
+	err := c.client.Del(ctx, emailCodeKey(purpose, email)).Err()
 	// If the key does not exist, return nil
 	if err == redis.Nil {
 		return nil
 	}
-	// If there is an error other than key not found, return the error
+	return err
+}
+
+// GetEmailCodeCooldown returns how long the running cooldown of the email lasts, or 0 if there is none
+func (c *RedisCacher) GetEmailCodeCooldown(ctx context.Context, email string) (time.Duration, error) {
+	if c.emailCodeCooldown <= 0 {
+		return 0, nil
+	}
+
+	ttl, err := c.client.PTTL(ctx, emailCooldownKey(email)).Result()
 	if err != nil {
-		return err
+		return 0, err
 	}
+	// Negative if there is no cooldown
+	return max(ttl, 0), nil
+}
 
-	// Successfully deleted the email code from cache, return nil
-	return nil
+// StartEmailCodeCooldown starts the cooldown before another code can be sent to the email
+func (c *RedisCacher) StartEmailCodeCooldown(ctx context.Context, email string) error {
+	if c.emailCodeCooldown <= 0 {
+		return nil
+	}
+	return c.client.Set(ctx, emailCooldownKey(email), 1, c.emailCodeCooldown).Err()
 }
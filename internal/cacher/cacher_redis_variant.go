@@ -0,0 +1,44 @@
+package cacher
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Hash counting the redirects to each variant since the last flush to the database
+const variantClicksKey = "variantClicks"
+
+// IncrVariantClicks adds clicks to the counter of the variant
+func (c *RedisCacher) IncrVariantClicks(ctx context.Context, variantID int64, clicks int64) error {
+	return c.client.HIncrBy(ctx, variantClicksKey, strconv.FormatInt(variantID, 10), clicks).Err()
+}
+
+// TakeVariantClicks returns the counted clicks by variant ID and resets the counters
+func (c *RedisCacher) TakeVariantClicks(ctx context.Context) (map[int64]int64, error) {
+	// Read and delete in one transaction, so that no click counted in between is lost
+	var counts *redis.MapStringStringCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		counts = pipe.HGetAll(ctx, variantClicksKey)
+		pipe.Del(ctx, variantClicksKey)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clicks := make(map[int64]int64, len(counts.Val()))
+	for field, value := range counts.Val() {
+		variantID, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		clicks[variantID] = count
+	}
+	return clicks, nil
+}
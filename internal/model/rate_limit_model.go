@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Kinds of clients, each one with its own budget
+const (
+	RateLimitAnonymous     = "anonymous"
+	RateLimitAuthenticated = "user"
+	RateLimitAPIKey        = "api_key"
+)
+
+// RateLimitClient identifies who sent a request
+type RateLimitClient struct {
+	IP string
+	// Empty for anonymous requests
+	UserID string
+	// API key sent with the request, only trusted if it is configured
+	APIKey string
+}
+
+// RateLimitResult is the state of the budget of a client after a request
+type RateLimitResult struct {
+	Allowed bool
+	// Policy name, requests per window and the window
+	Policy string
+	Limit  int
+	Window time.Duration
+	// Requests left, how long until the next request is allowed, and how long until the budget is full again
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
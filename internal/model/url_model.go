@@ -1,11 +1,73 @@
 package model
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/ZureTz/shorter-url/internal/repo"
 )
 
+// How a short URL redirects to its destination
+const (
+	RedirectMovedPermanently = "301"
+	RedirectFound            = "302"
+	RedirectTemporary        = "307"
+	RedirectPermanent        = "308"
+	RedirectMetaRefresh      = "meta"
+	DefaultRedirectType      = RedirectFound
+)
+
+// Whether the query string of a short URL is forwarded to its destination
+const (
+	// The query string of the short URL is dropped
+	QueryPassthroughNone = "none"
+	// Parameters are added unless the destination already has them
+	QueryPassthroughAppend = "append"
+	// Parameters replace those of the destination with the same name
+	QueryPassthroughOverride = "override"
+)
+
+// RedirectRule sends the visitors matching all of its conditions to another destination
+// Conditions that are not given match every visitor
+type RedirectRule struct {
+	// ISO 3166-1 country codes of the visitor, "EU" matches every member state of the European Union
+	Countries []string `json:"countries,omitempty" validate:"omitempty,dive,len=2,alpha"`
+	// Operating systems of the visitor
+	OS []string `json:"os,omitempty" validate:"omitempty,dive,oneof=ios android windows macos linux chromeos"`
+	// Kinds of devices of the visitor
+	Devices []string `json:"devices,omitempty" validate:"omitempty,dive,oneof=mobile desktop bot"`
+	// Language ranges matched against the preferred language of the visitor, e.g. "de" or "pt-BR"
+	Languages []string `json:"languages,omitempty" validate:"omitempty,dive,min=2,max=35"`
+	// Time window in which the rule applies
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+	// Where the matching visitors are sent
+	Destination string `json:"destination" validate:"required,http_url"`
+}
+
+// Visitor describes the client following a short URL, used to evaluate redirect rules
+type Visitor struct {
+	IP             string
+	UserAgent      string
+	AcceptLanguage string
+	// Variant the visitor was sent to before, 0 if none
+	VariantID int64
+}
+
+// URLVariant is one of the weighted destinations splitting the traffic of a short URL
+type URLVariant struct {
+	Destination string `json:"destination" validate:"required,http_url"`
+	// Share of the traffic relative to the other variants
+	Weight int32 `json:"weight" validate:"required,min=1,max=1000"`
+}
+
+// CachedURL is a short URL as stored in the cache, with everything needed to redirect
+type CachedURL struct {
+	repo.Url
+	// Empty unless the traffic is split across several destinations
+	Variants []repo.UrlVariant `json:"variants,omitempty"`
+}
+
 type CreateShortURLRequest struct {
 	// The original URL to be shortened
 	OriginalURL string `json:"original_url" validate:"required,http_url"`
@@ -13,8 +75,33 @@ type CreateShortURLRequest struct {
 	CustomCode string `json:"custom_code,omitempty" validate:"omitempty,alphanum,min=4,max=10"`
 	// Duration in hours for which the shortened URL will be valid
 	Duration *int `json:"duration,omitempty" validate:"omitempty,min=1,max=720"`
-	// Username of the user creating the shortened URL
-	CreatedBy string `json:"created_by" validate:"required,min=3,max=20,custom_username_validator"`
+	// Redirect status code, or "meta" for a page redirecting with a meta refresh
+	RedirectType string `json:"redirect_type,omitempty" validate:"omitempty,oneof=301 302 307 308 meta"`
+	// UTM parameters added to the destination when redirecting
+	UTMSource   string `json:"utm_source,omitempty" validate:"omitempty,max=100"`
+	UTMMedium   string `json:"utm_medium,omitempty" validate:"omitempty,max=100"`
+	UTMCampaign string `json:"utm_campaign,omitempty" validate:"omitempty,max=100"`
+	UTMTerm     string `json:"utm_term,omitempty" validate:"omitempty,max=100"`
+	UTMContent  string `json:"utm_content,omitempty" validate:"omitempty,max=100"`
+	// Whether the query string of the short URL is forwarded, defaults to "none"
+	QueryPassthrough string `json:"query_passthrough,omitempty" validate:"omitempty,oneof=none append override"`
+	// Destinations the traffic is split across, sticky per visitor
+	// Visitors matching a redirect rule are not part of the split
+	Variants []URLVariant `json:"variants,omitempty" validate:"omitempty,min=2,max=10,dive"`
+	// Rules evaluated in order when redirecting, visitors matching none of them go to the original URL
+	RedirectRules []RedirectRule `json:"redirect_rules,omitempty" validate:"omitempty,max=20,dive"`
+	// Links opening the native app, visitors without the app fall back to the destination
+	IOSDeepLink     string `json:"ios_deep_link,omitempty" validate:"omitempty,max=2048,custom_deep_link_validator"`
+	AndroidDeepLink string `json:"android_deep_link,omitempty" validate:"omitempty,max=2048,custom_deep_link_validator"`
+	// Title shown on the preview page of the shortened URL, if provided
+	Title string `json:"title,omitempty" validate:"omitempty,max=200"`
+	// Username of the user creating the shortened URL, taken from the JWT by the handler
+	CreatedBy string `json:"-"`
+	// Return the user's existing short URL of the same target instead of creating a new one
+	// Falls back to the user's setting if not provided
+	ReuseExisting *bool `json:"reuse_existing,omitempty"`
+	// Value of the Idempotency-Key header, retries with the same key return the first result
+	IdempotencyKey string `json:"-" validate:"omitempty,max=255"`
 }
 
 type CreateShortURLResponse struct {
@@ -22,6 +109,70 @@ type CreateShortURLResponse struct {
 	ShortURL string `json:"short_url"`
 	// The expiration date and time of the shortened URL
 	ExpiredAt time.Time `json:"expired_at"`
+	// Whether an existing short URL was returned instead of creating a new one
+	Reused bool `json:"reused"`
+	// Whether the destination was flagged as malicious, quarantined URLs show a warning instead of redirecting
+	Quarantined      bool   `json:"quarantined"`
+	QuarantineReason string `json:"quarantine_reason,omitempty"`
+}
+
+type LongURLInfo struct {
+	// The destination of the short URL, or of the redirect rule matching the visitor
+	OriginalURL string
+	// Whether the destination depends on the visitor through redirect rules or variants
+	// Such redirects must not be cached
+	PerVisitor bool
+	// Variant the visitor was sent to, 0 if the traffic is not split
+	VariantID int64
+	// Link opening the native app on the device of the visitor, empty if there is none
+	DeepLink string
+	// How to redirect to the destination, one of the Redirect constants
+	RedirectType string
+	// Zero if the short URL never expires
+	ExpiredAt time.Time
+	// UTM parameters set on the destination, overriding its own
+	UTMParams url.Values
+	// How the query string of the short URL is forwarded, one of the QueryPassthrough constants
+	QueryPassthrough string
+	// Quarantined URLs show a warning page instead of redirecting
+	Quarantined      bool
+	QuarantineReason string
+}
+
+type URLPreviewInfo struct {
+	// The shortened URL and its destination
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	// Title given by the owner, empty if not provided
+	Title     string     `json:"title"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiredAt *time.Time `json:"expired_at"`
+	// Whether the destination was flagged as malicious
+	Quarantined      bool   `json:"quarantined"`
+	QuarantineReason string `json:"quarantine_reason,omitempty"`
+}
+
+type QRCodeRequest struct {
+	// Id of the shortened URL, only used by the route of the user's URLs
+	ID int64 `param:"id"`
+	// Width and height of the image in pixels
+	Size int `query:"size" validate:"omitempty,min=64,max=4096"`
+	// Error correction level
+	Level string `query:"level" validate:"omitempty,oneof=L M Q H l m q h"`
+	// Colors as RRGGBB hex values
+	Foreground string `query:"fg" validate:"omitempty,hexadecimal,len=6"`
+	Background string `query:"bg" validate:"omitempty,hexadecimal,len=6"`
+	// Width of the quiet zone around the code, in modules
+	Margin *int `query:"margin" validate:"omitempty,min=0,max=16"`
+	// Image format, png or svg
+	Format string `query:"format" validate:"omitempty,oneof=png svg"`
+	// Draw the configured logo in the center
+	Logo bool `query:"logo"`
+}
+
+type QRCodeImage struct {
+	Data        []byte
+	ContentType string
 }
 
 type GetUserShortURLsRequest struct {
@@ -33,17 +184,36 @@ type GetUserShortURLsRequest struct {
 
 type GetUserShortURLsResponse struct {
 	// List of shortened URLs created by the user
-	URLs []repo.Url `json:"urls"`
+	URLs []UserShortURL `json:"urls"`
+}
+
+type UserShortURL struct {
+	repo.Url
+	// Whether the destination failed its latest health check
+	IsBroken bool `json:"is_broken"`
+	// Latest health check of the destination, nil if it was not checked yet
+	Health *repo.UrlHealthCheck `json:"health,omitempty"`
+	// Destinations the traffic is split across, with the clicks of each
+	Variants []repo.UrlVariant `json:"variants,omitempty"`
+	// Title, description and Open Graph tags of the destination, nil if they were not fetched yet
+	Preview *repo.UrlPreview `json:"preview,omitempty"`
 }
 
 type DeleteUserShortURLRequest struct {
 	// Id of the shortened URL to be deleted in the database
 	ID int64 `json:"id" validate:"required,min=1"`
 	// Short code of the URL to be deleted in the cache
-	ShortCode string `json:"short_code" validate:"required,alphanum,min=4,max=10"`
+	ShortCode string `json:"short_code" validate:"required,custom_short_code_validator"`
 }
 
 type DeleteUserShortURLResponse struct {
 	// Success message
 	Message string `json:"message"`
 }
+
+type ExtendShortURLResponse struct {
+	// The extended short URL
+	ShortURL string `json:"short_url"`
+	// The new expiration date and time, nil if the short URL no longer expires
+	ExpiredAt *time.Time `json:"expired_at"`
+}
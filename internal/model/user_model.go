@@ -5,6 +5,8 @@ import "time"
 type LoginRequest struct {
 	Username string `json:"username" validate:"required,min=3,max=20,custom_username_validator"`
 	Password string `json:"password" validate:"required,min=6,max=50,custom_password_validator"`
+	// Address of the client, set by the handler
+	ClientIP string `json:"-"`
 }
 
 type LoginResponse struct {
@@ -21,10 +23,25 @@ type RegisterRequest struct {
 	ConfirmedPassword string `json:"confirmed_password" validate:"required,eqfield=Password"`
 	Email             string `json:"email" validate:"required,email"`
 	EmailCode         string `json:"email_code" validate:"required,len=6,numeric"`
+	// Preferred languages of the emails sent to the user, as in an Accept-Language header
+	Language string `json:"language" validate:"max=100"`
+	ClientIP string `json:"-"`
 }
 
+// What an email code can be used for
+const (
+	EmailCodeRegister      = "register"
+	EmailCodeResetPassword = "reset_password"
+	// Sent to the current and the new address when changing the email, only requested by logged in users
+	EmailCodeChangeEmailCurrent = "change_email_current"
+	EmailCodeChangeEmailNew     = "change_email_new"
+)
+
 type GetEmailCodeRequest struct {
-	Email string `json:"email" validate:"required,email"`
+	Email   string `json:"email" validate:"required,email"`
+	Purpose string `json:"purpose" validate:"required,oneof=register reset_password"`
+	// Preferred languages of the email, as in an Accept-Language header
+	Language string `json:"language" validate:"max=100"`
 }
 
 type ResetPasswordRequest struct {
@@ -32,4 +49,61 @@ type ResetPasswordRequest struct {
 	EmailCode         string `json:"email_code" validate:"required,len=6,numeric"`
 	Password          string `json:"password" validate:"required,min=6,max=50,custom_password_validator"`
 	ConfirmedPassword string `json:"confirmed_password" validate:"required,eqfield=Password"`
+	ClientIP          string `json:"-"`
+}
+
+type RequestEmailChangeRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	// Taken from the JWT by the handler
+	UserID string `json:"-"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" validate:"required,email"`
+	// Codes sent to the current and the new address
+	CurrentEmailCode string `json:"current_email_code" validate:"required,len=6,numeric"`
+	NewEmailCode     string `json:"new_email_code" validate:"required,len=6,numeric"`
+	UserID           string `json:"-"`
+	ClientIP         string `json:"-"`
+}
+
+type DeleteAccountRequest struct {
+	// The password is asked again before deleting the account
+	Password string `json:"password" validate:"required,max=50"`
+	// Whether the short URLs are deleted too, otherwise they keep working without an owner
+	DeleteURLs bool   `json:"delete_urls"`
+	UserID     string `json:"-"`
+	ClientIP   string `json:"-"`
+}
+
+type UserProfile struct {
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	// Languages of the emails sent to the user
+	Language string `json:"language"`
+	// IANA time zone the times in emails are shown in
+	Timezone string `json:"timezone"`
+	// Defaults of the short URLs created by the user, the server defaults are used if not set
+	DefaultDuration     *int      `json:"default_duration"`
+	DefaultRedirectType string    `json:"default_redirect_type"`
+	ReuseExistingURLs   bool      `json:"reuse_existing_urls"`
+	ExpiryReminders     bool      `json:"expiry_reminders"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// Fields left out of the request are not changed
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,max=50"`
+	// Preferred languages of the emails, as in an Accept-Language header
+	Language *string `json:"language" validate:"omitempty,max=100"`
+	Timezone *string `json:"timezone" validate:"omitempty,timezone"`
+	// Duration in days of new short URLs, 0 to use the server default
+	DefaultDuration *int `json:"default_duration" validate:"omitempty,min=0,max=720"`
+	// Redirect type of new short URLs, empty to use the server default
+	DefaultRedirectType *string `json:"default_redirect_type" validate:"omitempty,oneof='' 301 302 307 308 meta"`
+	ReuseExistingURLs   *bool   `json:"reuse_existing_urls"`
+	ExpiryReminders     *bool   `json:"expiry_reminders"`
+	UserID              string  `json:"-"`
 }
@@ -6,24 +6,114 @@ package repo
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
+type MailDeadLetter struct {
+	ID        int64     `json:"id"`
+	Recipient string    `json:"recipient"`
+	Subject   string    `json:"subject"`
+	TextBody  string    `json:"text_body"`
+	HtmlBody  string    `json:"html_body"`
+	Attempts  int32     `json:"attempts"`
+	LastError string    `json:"last_error"`
+	QueuedAt  time.Time `json:"queued_at"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+type MailQueue struct {
+	ID              int64        `json:"id"`
+	Recipient       string       `json:"recipient"`
+	Subject         string       `json:"subject"`
+	TextBody        string       `json:"text_body"`
+	HtmlBody        string       `json:"html_body"`
+	Attempts        int32        `json:"attempts"`
+	LastError       string       `json:"last_error"`
+	NextAttemptAt   time.Time    `json:"next_attempt_at"`
+	LockedUntil     sql.NullTime `json:"locked_until"`
+	CreatedAt       time.Time    `json:"created_at"`
+	ListUnsubscribe string       `json:"list_unsubscribe"`
+	ExpiresAt       sql.NullTime `json:"expires_at"`
+}
+
+type ShortCodePool struct {
+	Code      string    `json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UrlHealthCheck struct {
+	UrlID               int64           `json:"url_id"`
+	StatusCode          int32           `json:"status_code"`
+	LatencyMs           int32           `json:"latency_ms"`
+	RedirectChain       json.RawMessage `json:"redirect_chain"`
+	Error               string          `json:"error"`
+	IsBroken            bool            `json:"is_broken"`
+	ConsecutiveFailures int32           `json:"consecutive_failures"`
+	CheckedAt           time.Time       `json:"checked_at"`
+	NotifiedAt          sql.NullTime    `json:"notified_at"`
+}
+
 type Url struct {
-	ID          int64          `json:"id"`
-	OriginalUrl string         `json:"original_url"`
-	ShortCode   string         `json:"short_code"`
-	IsCustom    bool           `json:"is_custom"`
-	CreatedAt   time.Time      `json:"created_at"`
-	ExpiredAt   sql.NullTime   `json:"expired_at"`
-	CreatedBy   sql.NullString `json:"created_by"`
+	ID               int64           `json:"id"`
+	OriginalUrl      string          `json:"original_url"`
+	ShortCode        string          `json:"short_code"`
+	IsCustom         bool            `json:"is_custom"`
+	CreatedAt        time.Time       `json:"created_at"`
+	ExpiredAt        sql.NullTime    `json:"expired_at"`
+	CreatedBy        sql.NullString  `json:"created_by"`
+	CanonicalUrl     string          `json:"canonical_url"`
+	QuarantinedAt    sql.NullTime    `json:"quarantined_at"`
+	QuarantineReason string          `json:"quarantine_reason"`
+	Title            string          `json:"title"`
+	RedirectType     string          `json:"redirect_type"`
+	UtmSource        string          `json:"utm_source"`
+	UtmMedium        string          `json:"utm_medium"`
+	UtmCampaign      string          `json:"utm_campaign"`
+	UtmTerm          string          `json:"utm_term"`
+	UtmContent       string          `json:"utm_content"`
+	QueryPassthrough string          `json:"query_passthrough"`
+	RedirectRules    json.RawMessage `json:"redirect_rules"`
+	IosDeepLink      string          `json:"ios_deep_link"`
+	AndroidDeepLink  string          `json:"android_deep_link"`
+	ExpiryRemindedAt sql.NullTime    `json:"expiry_reminded_at"`
+	ScreenedAt       sql.NullTime    `json:"screened_at"`
+}
+
+type UrlPreview struct {
+	UrlID         int64     `json:"url_id"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	OgTitle       string    `json:"og_title"`
+	OgDescription string    `json:"og_description"`
+	OgImage       string    `json:"og_image"`
+	OgSiteName    string    `json:"og_site_name"`
+	FaviconUrl    string    `json:"favicon_url"`
+	FetchError    string    `json:"fetch_error"`
+	FetchedAt     time.Time `json:"fetched_at"`
+}
+
+type UrlVariant struct {
+	ID          int64     `json:"id"`
+	UrlID       int64     `json:"url_id"`
+	Destination string    `json:"destination"`
+	Weight      int32     `json:"weight"`
+	Clicks      int64     `json:"clicks"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type User struct {
-	ID           int64     `json:"id"`
-	UserID       string    `json:"user_id"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"password_hash"`
-	Email        string    `json:"email"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID                  int64         `json:"id"`
+	UserID              string        `json:"user_id"`
+	Username            string        `json:"username"`
+	PasswordHash        string        `json:"password_hash"`
+	Email               string        `json:"email"`
+	CreatedAt           time.Time     `json:"created_at"`
+	ReuseExistingUrls   bool          `json:"reuse_existing_urls"`
+	Language            string        `json:"language"`
+	ExpiryReminders     bool          `json:"expiry_reminders"`
+	DisplayName         string        `json:"display_name"`
+	Timezone            string        `json:"timezone"`
+	DefaultDuration     sql.NullInt32 `json:"default_duration"`
+	DefaultRedirectType string        `json:"default_redirect_type"`
 }
@@ -6,21 +6,62 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	AddShortCodeToPool(ctx context.Context, code string) (int64, error)
+	AddURLVariantClicks(ctx context.Context, arg AddURLVariantClicksParams) error
+	ClaimDueMail(ctx context.Context, arg ClaimDueMailParams) ([]MailQueue, error)
+	CountPooledShortCodes(ctx context.Context) (int64, error)
 	CreateNewUser(ctx context.Context, arg CreateNewUserParams) error
 	CreateURL(ctx context.Context, arg CreateURLParams) (Url, error)
+	CreateURLVariant(ctx context.Context, arg CreateURLVariantParams) (UrlVariant, error)
+	DeleteMail(ctx context.Context, id int64) error
+	DeleteOldDeadLetters(ctx context.Context, failedAt time.Time) (int64, error)
 	DeleteOutdatedURLs(ctx context.Context) error
 	DeleteURLFromId(ctx context.Context, arg DeleteURLFromIdParams) error
+	DeleteUsedPooledShortCodes(ctx context.Context) (int64, error)
+	DeleteUser(ctx context.Context, userID string) error
+	DeleteUserURLs(ctx context.Context, createdBy sql.NullString) error
+	EnqueueMail(ctx context.Context, arg EnqueueMailParams) error
+	ExtendURLExpiration(ctx context.Context, arg ExtendURLExpirationParams) (Url, error)
+	GetRecentActiveURLVariants(ctx context.Context, limit int32) ([]UrlVariant, error)
+	GetRecentActiveURLs(ctx context.Context, limit int32) ([]Url, error)
 	GetURLByShortCode(ctx context.Context, shortCode string) (Url, error)
+	GetURLHealthChecksByURLIDs(ctx context.Context, urlIds []int64) ([]UrlHealthCheck, error)
+	GetURLPreviewsByURLIDs(ctx context.Context, urlIds []int64) ([]UrlPreview, error)
+	GetURLVariants(ctx context.Context, urlID int64) ([]UrlVariant, error)
+	GetURLVariantsByURLIDs(ctx context.Context, urlIds []int64) ([]UrlVariant, error)
+	GetURLsDueForHealthCheck(ctx context.Context, arg GetURLsDueForHealthCheckParams) ([]GetURLsDueForHealthCheckRow, error)
+	GetURLsDueForScreening(ctx context.Context, arg GetURLsDueForScreeningParams) ([]GetURLsDueForScreeningRow, error)
+	GetURLsExpiringSoon(ctx context.Context, arg GetURLsExpiringSoonParams) ([]GetURLsExpiringSoonRow, error)
+	GetUserActiveURLByCanonicalURL(ctx context.Context, arg GetUserActiveURLByCanonicalURLParams) (Url, error)
 	GetUserInfoFromEmail(ctx context.Context, email string) (User, error)
 	GetUserInfoFromUserID(ctx context.Context, userID string) (User, error)
 	GetUserInfoFromUsername(ctx context.Context, username string) (User, error)
+	GetUserShortCodes(ctx context.Context, createdBy sql.NullString) ([]string, error)
 	GetUserShortURLs(ctx context.Context, arg GetUserShortURLsParams) ([]Url, error)
+	GetUserURLByID(ctx context.Context, arg GetUserURLByIDParams) (Url, error)
 	IsNewUserAvailable(ctx context.Context, arg IsNewUserAvailableParams) (bool, error)
 	IsShortCodeAvailable(ctx context.Context, shortCode string) (bool, error)
+	MarkURLExpiryReminded(ctx context.Context, id int64) error
+	MarkURLHealthNotified(ctx context.Context, urlID int64) error
+	MarkURLScreened(ctx context.Context, id int64) error
+	MoveMailToDeadLetters(ctx context.Context, arg MoveMailToDeadLettersParams) error
+	NextShortCodeNumber(ctx context.Context) (int64, error)
+	QuarantineURL(ctx context.Context, arg QuarantineURLParams) error
+	ReleaseMail(ctx context.Context, id int64) error
+	RemoveShortCodeFromPool(ctx context.Context, code string) error
 	ResetUserPassword(ctx context.Context, arg ResetUserPasswordParams) error
+	RetryMail(ctx context.Context, arg RetryMailParams) error
+	SetUserExpiryReminders(ctx context.Context, arg SetUserExpiryRemindersParams) error
+	TakePooledShortCode(ctx context.Context) (string, error)
+	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error)
+	UpsertURLHealthCheck(ctx context.Context, arg UpsertURLHealthCheckParams) (UrlHealthCheck, error)
+	UpsertURLPreview(ctx context.Context, arg UpsertURLPreviewParams) error
 }
 
 var _ Querier = (*Queries)(nil)
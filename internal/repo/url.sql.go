@@ -8,6 +8,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 )
 
 const createURL = `-- name: CreateURL :one
@@ -16,18 +17,46 @@ insert into urls (
   short_code,
   is_custom,
   expired_at,
-  created_by
+  created_by,
+  canonical_url,
+  quarantined_at,
+  quarantine_reason,
+  title,
+  redirect_type,
+  utm_source,
+  utm_medium,
+  utm_campaign,
+  utm_term,
+  utm_content,
+  query_passthrough,
+  redirect_rules,
+  ios_deep_link,
+  android_deep_link
 ) values (
-  $1, $2, $3, $4, $5
-) returning id, original_url, short_code, is_custom, created_at, expired_at, created_by
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
+) returning id, original_url, short_code, is_custom, created_at, expired_at, created_by, canonical_url, quarantined_at, quarantine_reason, title, redirect_type, utm_source, utm_medium, utm_campaign, utm_term, utm_content, query_passthrough, redirect_rules, ios_deep_link, android_deep_link, expiry_reminded_at, screened_at
 `
 
 type CreateURLParams struct {
-	OriginalUrl string         `json:"original_url"`
-	ShortCode   string         `json:"short_code"`
-	IsCustom    bool           `json:"is_custom"`
-	ExpiredAt   sql.NullTime   `json:"expired_at"`
-	CreatedBy   sql.NullString `json:"created_by"`
+	OriginalUrl      string          `json:"original_url"`
+	ShortCode        string          `json:"short_code"`
+	IsCustom         bool            `json:"is_custom"`
+	ExpiredAt        sql.NullTime    `json:"expired_at"`
+	CreatedBy        sql.NullString  `json:"created_by"`
+	CanonicalUrl     string          `json:"canonical_url"`
+	QuarantinedAt    sql.NullTime    `json:"quarantined_at"`
+	QuarantineReason string          `json:"quarantine_reason"`
+	Title            string          `json:"title"`
+	RedirectType     string          `json:"redirect_type"`
+	UtmSource        string          `json:"utm_source"`
+	UtmMedium        string          `json:"utm_medium"`
+	UtmCampaign      string          `json:"utm_campaign"`
+	UtmTerm          string          `json:"utm_term"`
+	UtmContent       string          `json:"utm_content"`
+	QueryPassthrough string          `json:"query_passthrough"`
+	RedirectRules    json.RawMessage `json:"redirect_rules"`
+	IosDeepLink      string          `json:"ios_deep_link"`
+	AndroidDeepLink  string          `json:"android_deep_link"`
 }
 
 func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, error) {
@@ -37,6 +66,20 @@ func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, erro
 		arg.IsCustom,
 		arg.ExpiredAt,
 		arg.CreatedBy,
+		arg.CanonicalUrl,
+		arg.QuarantinedAt,
+		arg.QuarantineReason,
+		arg.Title,
+		arg.RedirectType,
+		arg.UtmSource,
+		arg.UtmMedium,
+		arg.UtmCampaign,
+		arg.UtmTerm,
+		arg.UtmContent,
+		arg.QueryPassthrough,
+		arg.RedirectRules,
+		arg.IosDeepLink,
+		arg.AndroidDeepLink,
 	)
 	var i Url
 	err := row.Scan(
@@ -47,6 +90,22 @@ func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, erro
 		&i.CreatedAt,
 		&i.ExpiredAt,
 		&i.CreatedBy,
+		&i.CanonicalUrl,
+		&i.QuarantinedAt,
+		&i.QuarantineReason,
+		&i.Title,
+		&i.RedirectType,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.UtmTerm,
+		&i.UtmContent,
+		&i.QueryPassthrough,
+		&i.RedirectRules,
+		&i.IosDeepLink,
+		&i.AndroidDeepLink,
+		&i.ExpiryRemindedAt,
+		&i.ScreenedAt,
 	)
 	return i, err
 }
@@ -84,9 +143,82 @@ func (q *Queries) DeleteURLFromId(ctx context.Context, arg DeleteURLFromIdParams
 	return err
 }
 
+const deleteUserURLs = `-- name: DeleteUserURLs :exec
+delete from
+  urls
+where
+  created_by = $1
+`
+
+func (q *Queries) DeleteUserURLs(ctx context.Context, createdBy sql.NullString) error {
+	_, err := q.db.ExecContext(ctx, deleteUserURLs, createdBy)
+	return err
+}
+
+const getRecentActiveURLs = `-- name: GetRecentActiveURLs :many
+select
+  id, original_url, short_code, is_custom, created_at, expired_at, created_by, canonical_url, quarantined_at, quarantine_reason, title, redirect_type, utm_source, utm_medium, utm_campaign, utm_term, utm_content, query_passthrough, redirect_rules, ios_deep_link, android_deep_link, expiry_reminded_at, screened_at
+from
+  urls
+where
+  expired_at is null
+  or
+  expired_at > current_timestamp
+order by
+  created_at desc
+limit $1
+`
+
+func (q *Queries) GetRecentActiveURLs(ctx context.Context, limit int32) ([]Url, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentActiveURLs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Url
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.OriginalUrl,
+			&i.ShortCode,
+			&i.IsCustom,
+			&i.CreatedAt,
+			&i.ExpiredAt,
+			&i.CreatedBy,
+			&i.CanonicalUrl,
+			&i.QuarantinedAt,
+			&i.QuarantineReason,
+			&i.Title,
+			&i.RedirectType,
+			&i.UtmSource,
+			&i.UtmMedium,
+			&i.UtmCampaign,
+			&i.UtmTerm,
+			&i.UtmContent,
+			&i.QueryPassthrough,
+			&i.RedirectRules,
+			&i.IosDeepLink,
+			&i.AndroidDeepLink,
+			&i.ExpiryRemindedAt,
+			&i.ScreenedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getURLByShortCode = `-- name: GetURLByShortCode :one
 select 
-  id, original_url, short_code, is_custom, created_at, expired_at, created_by 
+  id, original_url, short_code, is_custom, created_at, expired_at, created_by, canonical_url, quarantined_at, quarantine_reason, title, redirect_type, utm_source, utm_medium, utm_campaign, utm_term, utm_content, query_passthrough, redirect_rules, ios_deep_link, android_deep_link, expiry_reminded_at, screened_at 
 from 
   urls 
 where 
@@ -109,13 +241,118 @@ func (q *Queries) GetURLByShortCode(ctx context.Context, shortCode string) (Url,
 		&i.CreatedAt,
 		&i.ExpiredAt,
 		&i.CreatedBy,
+		&i.CanonicalUrl,
+		&i.QuarantinedAt,
+		&i.QuarantineReason,
+		&i.Title,
+		&i.RedirectType,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.UtmTerm,
+		&i.UtmContent,
+		&i.QueryPassthrough,
+		&i.RedirectRules,
+		&i.IosDeepLink,
+		&i.AndroidDeepLink,
+		&i.ExpiryRemindedAt,
+		&i.ScreenedAt,
+	)
+	return i, err
+}
+
+const getUserActiveURLByCanonicalURL = `-- name: GetUserActiveURLByCanonicalURL :one
+select
+  id, original_url, short_code, is_custom, created_at, expired_at, created_by, canonical_url, quarantined_at, quarantine_reason, title, redirect_type, utm_source, utm_medium, utm_campaign, utm_term, utm_content, query_passthrough, redirect_rules, ios_deep_link, android_deep_link, expiry_reminded_at, screened_at
+from
+  urls
+where
+  created_by = $1
+  and
+  canonical_url = $2
+  and
+  is_custom = false
+  and (
+    expired_at is null
+    or
+    expired_at > current_timestamp
+  )
+order by
+  created_at desc
+limit 1
+`
+
+type GetUserActiveURLByCanonicalURLParams struct {
+	CreatedBy    sql.NullString `json:"created_by"`
+	CanonicalUrl string         `json:"canonical_url"`
+}
+
+func (q *Queries) GetUserActiveURLByCanonicalURL(ctx context.Context, arg GetUserActiveURLByCanonicalURLParams) (Url, error) {
+	row := q.db.QueryRowContext(ctx, getUserActiveURLByCanonicalURL, arg.CreatedBy, arg.CanonicalUrl)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortCode,
+		&i.IsCustom,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+		&i.CreatedBy,
+		&i.CanonicalUrl,
+		&i.QuarantinedAt,
+		&i.QuarantineReason,
+		&i.Title,
+		&i.RedirectType,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.UtmTerm,
+		&i.UtmContent,
+		&i.QueryPassthrough,
+		&i.RedirectRules,
+		&i.IosDeepLink,
+		&i.AndroidDeepLink,
+		&i.ExpiryRemindedAt,
+		&i.ScreenedAt,
 	)
 	return i, err
 }
 
+const getUserShortCodes = `-- name: GetUserShortCodes :many
+select
+  short_code
+from
+  urls
+where
+  created_by = $1
+`
+
+func (q *Queries) GetUserShortCodes(ctx context.Context, createdBy sql.NullString) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getUserShortCodes, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var short_code string
+		if err := rows.Scan(&short_code); err != nil {
+			return nil, err
+		}
+		items = append(items, short_code)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserShortURLs = `-- name: GetUserShortURLs :many
 select 
-  id, original_url, short_code, is_custom, created_at, expired_at, created_by
+  id, original_url, short_code, is_custom, created_at, expired_at, created_by, canonical_url, quarantined_at, quarantine_reason, title, redirect_type, utm_source, utm_medium, utm_campaign, utm_term, utm_content, query_passthrough, redirect_rules, ios_deep_link, android_deep_link, expiry_reminded_at, screened_at
 from
   urls
 where 
@@ -153,6 +390,22 @@ func (q *Queries) GetUserShortURLs(ctx context.Context, arg GetUserShortURLsPara
 			&i.CreatedAt,
 			&i.ExpiredAt,
 			&i.CreatedBy,
+			&i.CanonicalUrl,
+			&i.QuarantinedAt,
+			&i.QuarantineReason,
+			&i.Title,
+			&i.RedirectType,
+			&i.UtmSource,
+			&i.UtmMedium,
+			&i.UtmCampaign,
+			&i.UtmTerm,
+			&i.UtmContent,
+			&i.QueryPassthrough,
+			&i.RedirectRules,
+			&i.IosDeepLink,
+			&i.AndroidDeepLink,
+			&i.ExpiryRemindedAt,
+			&i.ScreenedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -167,6 +420,53 @@ func (q *Queries) GetUserShortURLs(ctx context.Context, arg GetUserShortURLsPara
 	return items, nil
 }
 
+const getUserURLByID = `-- name: GetUserURLByID :one
+select
+  id, original_url, short_code, is_custom, created_at, expired_at, created_by, canonical_url, quarantined_at, quarantine_reason, title, redirect_type, utm_source, utm_medium, utm_campaign, utm_term, utm_content, query_passthrough, redirect_rules, ios_deep_link, android_deep_link, expiry_reminded_at, screened_at
+from
+  urls
+where
+  id = $1
+  and
+  created_by = $2
+`
+
+type GetUserURLByIDParams struct {
+	ID        int64          `json:"id"`
+	CreatedBy sql.NullString `json:"created_by"`
+}
+
+func (q *Queries) GetUserURLByID(ctx context.Context, arg GetUserURLByIDParams) (Url, error) {
+	row := q.db.QueryRowContext(ctx, getUserURLByID, arg.ID, arg.CreatedBy)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.OriginalUrl,
+		&i.ShortCode,
+		&i.IsCustom,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+		&i.CreatedBy,
+		&i.CanonicalUrl,
+		&i.QuarantinedAt,
+		&i.QuarantineReason,
+		&i.Title,
+		&i.RedirectType,
+		&i.UtmSource,
+		&i.UtmMedium,
+		&i.UtmCampaign,
+		&i.UtmTerm,
+		&i.UtmContent,
+		&i.QueryPassthrough,
+		&i.RedirectRules,
+		&i.IosDeepLink,
+		&i.AndroidDeepLink,
+		&i.ExpiryRemindedAt,
+		&i.ScreenedAt,
+	)
+	return i, err
+}
+
 const isShortCodeAvailable = `-- name: IsShortCodeAvailable :one
 select not exists (
   select 
@@ -184,3 +484,22 @@ func (q *Queries) IsShortCodeAvailable(ctx context.Context, shortCode string) (b
 	err := row.Scan(&is_available)
 	return is_available, err
 }
+
+const quarantineURL = `-- name: QuarantineURL :exec
+update urls
+set
+  quarantined_at = current_timestamp,
+  quarantine_reason = $2
+where
+  id = $1
+`
+
+type QuarantineURLParams struct {
+	ID               int64  `json:"id"`
+	QuarantineReason string `json:"quarantine_reason"`
+}
+
+func (q *Queries) QuarantineURL(ctx context.Context, arg QuarantineURLParams) error {
+	_, err := q.db.ExecContext(ctx, quarantineURL, arg.ID, arg.QuarantineReason)
+	return err
+}
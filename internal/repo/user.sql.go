@@ -7,6 +7,7 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createNewUser = `-- name: CreateNewUser :exec
@@ -14,9 +15,10 @@ insert into users (
   user_id,
   username,
   password_hash,
-  email
+  email,
+  language
 ) values (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5
 )
 `
 
@@ -25,6 +27,7 @@ type CreateNewUserParams struct {
 	Username     string `json:"username"`
 	PasswordHash string `json:"password_hash"`
 	Email        string `json:"email"`
+	Language     string `json:"language"`
 }
 
 func (q *Queries) CreateNewUser(ctx context.Context, arg CreateNewUserParams) error {
@@ -33,13 +36,26 @@ func (q *Queries) CreateNewUser(ctx context.Context, arg CreateNewUserParams) er
 		arg.Username,
 		arg.PasswordHash,
 		arg.Email,
+		arg.Language,
 	)
 	return err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+delete from
+  users
+where
+  user_id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, userID string) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, userID)
+	return err
+}
+
 const getUserInfoFromEmail = `-- name: GetUserInfoFromEmail :one
 select
-  id, user_id, username, password_hash, email, created_at
+  id, user_id, username, password_hash, email, created_at, reuse_existing_urls, language, expiry_reminders, display_name, timezone, default_duration, default_redirect_type
 from
   users
 where
@@ -56,13 +72,20 @@ func (q *Queries) GetUserInfoFromEmail(ctx context.Context, email string) (User,
 		&i.PasswordHash,
 		&i.Email,
 		&i.CreatedAt,
+		&i.ReuseExistingUrls,
+		&i.Language,
+		&i.ExpiryReminders,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DefaultDuration,
+		&i.DefaultRedirectType,
 	)
 	return i, err
 }
 
 const getUserInfoFromUserID = `-- name: GetUserInfoFromUserID :one
 select
-  id, user_id, username, password_hash, email, created_at
+  id, user_id, username, password_hash, email, created_at, reuse_existing_urls, language, expiry_reminders, display_name, timezone, default_duration, default_redirect_type
 from
   users
 where
@@ -79,13 +102,20 @@ func (q *Queries) GetUserInfoFromUserID(ctx context.Context, userID string) (Use
 		&i.PasswordHash,
 		&i.Email,
 		&i.CreatedAt,
+		&i.ReuseExistingUrls,
+		&i.Language,
+		&i.ExpiryReminders,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DefaultDuration,
+		&i.DefaultRedirectType,
 	)
 	return i, err
 }
 
 const getUserInfoFromUsername = `-- name: GetUserInfoFromUsername :one
 select
-  id, user_id, username, password_hash, email, created_at
+  id, user_id, username, password_hash, email, created_at, reuse_existing_urls, language, expiry_reminders, display_name, timezone, default_duration, default_redirect_type
 from
   users
 where
@@ -102,6 +132,13 @@ func (q *Queries) GetUserInfoFromUsername(ctx context.Context, username string)
 		&i.PasswordHash,
 		&i.Email,
 		&i.CreatedAt,
+		&i.ReuseExistingUrls,
+		&i.Language,
+		&i.ExpiryReminders,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DefaultDuration,
+		&i.DefaultRedirectType,
 	)
 	return i, err
 }
@@ -147,3 +184,95 @@ func (q *Queries) ResetUserPassword(ctx context.Context, arg ResetUserPasswordPa
 	_, err := q.db.ExecContext(ctx, resetUserPassword, arg.PasswordHash, arg.Email)
 	return err
 }
+
+const setUserExpiryReminders = `-- name: SetUserExpiryReminders :exec
+update users
+set
+  expiry_reminders = $2
+where
+  user_id = $1
+`
+
+type SetUserExpiryRemindersParams struct {
+	UserID          string `json:"user_id"`
+	ExpiryReminders bool   `json:"expiry_reminders"`
+}
+
+func (q *Queries) SetUserExpiryReminders(ctx context.Context, arg SetUserExpiryRemindersParams) error {
+	_, err := q.db.ExecContext(ctx, setUserExpiryReminders, arg.UserID, arg.ExpiryReminders)
+	return err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :exec
+update users
+set
+  email = $2
+where
+  user_id = $1
+`
+
+type UpdateUserEmailParams struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserEmail, arg.UserID, arg.Email)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+update users
+set
+  display_name = $2,
+  language = $3,
+  timezone = $4,
+  default_duration = $5,
+  default_redirect_type = $6,
+  reuse_existing_urls = $7,
+  expiry_reminders = $8
+where
+  user_id = $1
+returning id, user_id, username, password_hash, email, created_at, reuse_existing_urls, language, expiry_reminders, display_name, timezone, default_duration, default_redirect_type
+`
+
+type UpdateUserProfileParams struct {
+	UserID              string        `json:"user_id"`
+	DisplayName         string        `json:"display_name"`
+	Language            string        `json:"language"`
+	Timezone            string        `json:"timezone"`
+	DefaultDuration     sql.NullInt32 `json:"default_duration"`
+	DefaultRedirectType string        `json:"default_redirect_type"`
+	ReuseExistingUrls   bool          `json:"reuse_existing_urls"`
+	ExpiryReminders     bool          `json:"expiry_reminders"`
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.UserID,
+		arg.DisplayName,
+		arg.Language,
+		arg.Timezone,
+		arg.DefaultDuration,
+		arg.DefaultRedirectType,
+		arg.ReuseExistingUrls,
+		arg.ExpiryReminders,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Username,
+		&i.PasswordHash,
+		&i.Email,
+		&i.CreatedAt,
+		&i.ReuseExistingUrls,
+		&i.Language,
+		&i.ExpiryReminders,
+		&i.DisplayName,
+		&i.Timezone,
+		&i.DefaultDuration,
+		&i.DefaultRedirectType,
+	)
+	return i, err
+}
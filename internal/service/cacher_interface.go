@@ -2,17 +2,51 @@ package service
 
 import (
 	"context"
-	"github.com/ZureTz/shorter-url/internal/repo"
+	"time"
+
+	"github.com/ZureTz/shorter-url/internal/model"
 )
 
 type Cacher interface {
 	// For URL service
-	GetURLFromCache(ctx context.Context, shortCode string) (*repo.Url, error)
-	StoreURLToCache(ctx context.Context, urlInfo repo.Url) error
+	GetURLFromCache(ctx context.Context, shortCode string) (*model.CachedURL, error)
+	StoreURLToCache(ctx context.Context, urlInfo model.CachedURL) error
 	DeleteURLFromCache(ctx context.Context, shortCode string) error
+	StoreURLsToCache(ctx context.Context, urlInfos []model.CachedURL) error
+
+	// For cache warm-up
+	IncrURLHits(ctx context.Context, shortCode string) error
+	GetHotShortCodes(ctx context.Context, limit int64) ([]string, error)
+	TrimHotShortCodes(ctx context.Context, keep int64) error
+	RemoveHotShortCodes(ctx context.Context, shortCodes ...string) error
+
+	// For counting the clicks of variants
+	IncrVariantClicks(ctx context.Context, variantID int64, clicks int64) error
+	TakeVariantClicks(ctx context.Context) (map[int64]int64, error)
+
+	// For idempotent requests
+	GetIdempotencyRecord(ctx context.Context, key string) ([]byte, error)
+	ReserveIdempotencyKey(ctx context.Context, key string, record []byte) (bool, error)
+	StoreIdempotencyRecord(ctx context.Context, key string, record []byte) error
+	DeleteIdempotencyRecord(ctx context.Context, key string) error
+
+	// For QR codes
+	GetQRCode(ctx context.Context, key string) ([]byte, error)
+	StoreQRCode(ctx context.Context, key string, image []byte) error
+
+	// For rate limiting
+	TakeRateLimitToken(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error)
+
+	// For brute-force protection
+	IncrFailedAttempts(ctx context.Context, key string, window time.Duration) (int64, error)
+	ClearFailedAttempts(ctx context.Context, key string) error
+	LockOut(ctx context.Context, key string, duration time.Duration) error
+	GetLockout(ctx context.Context, key string) (time.Duration, error)
 
 	// For User service
-	GetEmailUsingCode(ctx context.Context, emailCode string) (*string, error)
-	StoreCodeAndEmail(ctx context.Context, emailCode string, email string) error
-	DeleteEmailCode(ctx context.Context, emailCode string) error
+	GetEmailCode(ctx context.Context, purpose string, email string) (*string, error)
+	StoreEmailCode(ctx context.Context, purpose string, email string, emailCode string) error
+	DeleteEmailCode(ctx context.Context, purpose string, email string) error
+	GetEmailCodeCooldown(ctx context.Context, email string) (time.Duration, error)
+	StartEmailCodeCooldown(ctx context.Context, email string) error
 }
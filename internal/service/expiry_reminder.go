@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/jwt_gen"
+	"github.com/ZureTz/shorter-url/pkg/mailer"
+)
+
+// Defaults of the reminder settings that are not configured
+const (
+	expiryReminderDefaultInterval  = time.Hour
+	expiryReminderDefaultBatchSize = 500
+)
+
+var ErrInvalidEmailActionToken = errors.New("invalid or expired link")
+
+// URLExtender pushes back the expiry of short URLs
+type URLExtender interface {
+	ExtendShortURL(ctx context.Context, urlID int64, expiredAt time.Time, extendBy time.Duration) (*model.ExtendShortURLResponse, error)
+}
+
+// EmailActionSigner signs and verifies the tokens of the one-click links in emails
+type EmailActionSigner interface {
+	Sign(claims jwt_gen.EmailActionClaims) (string, error)
+	Parse(action string, tokenString string) (*jwt_gen.EmailActionClaims, error)
+}
+
+// ExpiryReminder emails owners a digest of their links expiring soon, with links to extend them
+// Each link is only reminded of once, until it is extended
+type ExpiryReminder struct {
+	querier     repo.Querier
+	urlExtender URLExtender
+	mailer      Mailer
+	signer      EmailActionSigner
+	baseURL     string
+	conf        config.ExpiryReminderConfig
+}
+
+// NewExpiryReminder creates the reminder, the links in the emails point to the base URL
+func NewExpiryReminder(db *sql.DB, urlExtender URLExtender, mailer Mailer, signer EmailActionSigner, baseURL string, conf config.ExpiryReminderConfig) *ExpiryReminder {
+	if conf.Interval <= 0 {
+		conf.Interval = expiryReminderDefaultInterval
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = expiryReminderDefaultBatchSize
+	}
+
+	return &ExpiryReminder{
+		querier:     repo.New(db),
+		urlExtender: urlExtender,
+		mailer:      mailer,
+		signer:      signer,
+		baseURL:     baseURL,
+		conf:        conf,
+	}
+}
+
+// Interval returns how often the links expiring soon are looked for
+func (r *ExpiryReminder) Interval() time.Duration {
+	return r.conf.Interval
+}
+
+// RemindExpiringLinks emails the owners of links expiring soon that were not reminded of yet
+// Owners whose links do not fit in the batch get the rest in another email on the next run
+func (r *ExpiryReminder) RemindExpiringLinks(ctx context.Context) error {
+	links, err := r.querier.GetURLsExpiringSoon(ctx, repo.GetURLsExpiringSoonParams{
+		ExpiringBefore: time.Now().UTC().Add(r.conf.RemindBefore),
+		MaxUrls:        int32(r.conf.BatchSize),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The links are ordered by owner, send one email per owner
+	for start := 0; start < len(links); {
+		end := start + 1
+		for end < len(links) && links[end].UserID == links[start].UserID {
+			end++
+		}
+
+		if err := r.remindOwner(ctx, links[start:end]); err != nil {
+			log.Printf("failed to remind %s of expiring links: %v", links[start].Username, err)
+		}
+		start = end
+	}
+
+	return nil
+}
+
+// remindOwner sends the digest of the links, which all belong to the same owner
+func (r *ExpiryReminder) remindOwner(ctx context.Context, links []repo.GetURLsExpiringSoonRow) error {
+	owner := links[0]
+
+	unsubscribeToken, err := r.signer.Sign(jwt_gen.EmailActionClaims{
+		Action: jwt_gen.ActionUnsubscribeExpiryReminders,
+		UserID: owner.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	data := mailer.LinkExpiringData{
+		Username:       owner.Username,
+		UnsubscribeURL: r.actionURL("/api/email/unsubscribe", unsubscribeToken),
+	}
+	// The expiries are shown in the time zone of the owner
+	location := userLocation(owner.Timezone)
+
+	for _, link := range links {
+		// The token carries the expiry, so it stops working once the link was extended
+		extendToken, err := r.signer.Sign(jwt_gen.EmailActionClaims{
+			Action:    jwt_gen.ActionExtendLink,
+			UserID:    owner.UserID,
+			URLID:     link.ID,
+			ExpiredAt: link.ExpiredAt.Time.UnixMicro(),
+		})
+		if err != nil {
+			return err
+		}
+
+		data.Links = append(data.Links, mailer.ExpiringLink{
+			ShortCode:   link.ShortCode,
+			ShortURL:    r.baseURL + "/" + link.ShortCode,
+			OriginalURL: link.OriginalUrl,
+			ExpiredAt:   link.ExpiredAt.Time.In(location),
+			ExtendURL:   r.actionURL("/api/email/extend", extendToken),
+		})
+	}
+
+	if err := r.mailer.SendTemplate(ctx, owner.Email, mailer.TemplateLinkExpiring, owner.Language, data); err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		if err := r.querier.MarkURLExpiryReminded(ctx, link.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// actionURL returns the address of the one-click link with the token
+func (r *ExpiryReminder) actionURL(path string, token string) string {
+	return r.baseURL + path + "?" + url.Values{"token": {token}}.Encode()
+}
+
+// ExtendLink extends the link of a token from a reminder email
+func (r *ExpiryReminder) ExtendLink(ctx context.Context, token string) (*model.ExtendShortURLResponse, error) {
+	claims, err := r.signer.Parse(jwt_gen.ActionExtendLink, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmailActionToken, err)
+	}
+
+	return r.urlExtender.ExtendShortURL(ctx, claims.URLID, time.UnixMicro(claims.ExpiredAt).UTC(), r.conf.ExtendBy)
+}
+
+// Unsubscribe turns off the reminders of the user of a token from a reminder email
+func (r *ExpiryReminder) Unsubscribe(ctx context.Context, token string) error {
+	claims, err := r.signer.Parse(jwt_gen.ActionUnsubscribeExpiryReminders, token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEmailActionToken, err)
+	}
+
+	return r.querier.SetUserExpiryReminders(ctx, repo.SetUserExpiryRemindersParams{
+		UserID:          claims.UserID,
+		ExpiryReminders: false,
+	})
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/mailer"
+	"github.com/ZureTz/shorter-url/pkg/safehttp"
+	"golang.org/x/time/rate"
+)
+
+// HTTPDoer sends HTTP requests
+// The health checker follows redirects itself, so the client should not follow them
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// NewHealthCheckHTTPClient creates the default client of the health checker
+// It does not follow redirects, and refuses to connect to internal addresses unless allowPrivate is set
+func NewHealthCheckHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	client := safehttp.NewClient(timeout, allowPrivate)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client
+}
+
+// healthCheckResult is the outcome of checking a single destination
+type healthCheckResult struct {
+	statusCode    int
+	latency       time.Duration
+	redirectChain []string
+	err           error
+}
+
+// broken reports whether the destination failed the check
+func (r healthCheckResult) broken() bool {
+	return r.err != nil || r.statusCode >= http.StatusBadRequest
+}
+
+// Defaults of the health check settings that are not configured
+const (
+	healthCheckDefaultInterval  = 10 * time.Minute
+	healthCheckDefaultBatchSize = 500
+	healthCheckDefaultTimeout   = 10 * time.Second
+	// Without a minimum every link would be checked again on every run
+	healthCheckDefaultRecheckAfter = 24 * time.Hour
+	// Without redirects every redirecting link would be marked broken
+	healthCheckDefaultMaxRedirects = 10
+)
+
+// errHealthCheckAborted is returned when a link could not be checked, which is not recorded as a failed check
+var errHealthCheckAborted = errors.New("health check aborted")
+
+// HealthChecker periodically checks whether the destinations of active links are reachable
+type HealthChecker struct {
+	querier repo.Querier
+	client  HTTPDoer
+	mailer  Mailer
+	conf    config.HealthCheckConfig
+
+	// Rate limiters for each destination host, dropped after every run so that only the hosts of one batch are kept
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
+}
+
+// NewHealthChecker creates a health checker sending requests with the given client
+func NewHealthChecker(db *sql.DB, client HTTPDoer, mailer Mailer, conf config.HealthCheckConfig) *HealthChecker {
+	if conf.Interval <= 0 {
+		conf.Interval = healthCheckDefaultInterval
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = healthCheckDefaultBatchSize
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = healthCheckDefaultTimeout
+	}
+	if conf.RecheckAfter <= 0 {
+		conf.RecheckAfter = healthCheckDefaultRecheckAfter
+	}
+	if conf.MaxRedirects <= 0 {
+		conf.MaxRedirects = healthCheckDefaultMaxRedirects
+	}
+
+	return &HealthChecker{
+		querier:  repo.New(db),
+		client:   client,
+		mailer:   mailer,
+		conf:     conf,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// Interval returns how often the links due for a check are checked
+func (h *HealthChecker) Interval() time.Duration {
+	return h.conf.Interval
+}
+
+// CheckDueURLs checks the links that have not been checked recently
+func (h *HealthChecker) CheckDueURLs(ctx context.Context) error {
+	// The rate limiters are recreated on the next run
+	defer func() {
+		h.limitersMu.Lock()
+		clear(h.limiters)
+		h.limitersMu.Unlock()
+	}()
+
+	dueURLs, err := h.querier.GetURLsDueForHealthCheck(ctx, repo.GetURLsDueForHealthCheckParams{
+		CheckedBefore: time.Now().UTC().Add(-h.conf.RecheckAfter),
+		MaxUrls:       int32(h.conf.BatchSize),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Check the links concurrently, the per-host rate limits keep single hosts from being flooded
+	jobs := make(chan repo.GetURLsDueForHealthCheckRow)
+	var wg sync.WaitGroup
+	for range max(h.conf.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for urlInfo := range jobs {
+				if err := h.checkURL(ctx, urlInfo); err != nil {
+					log.Printf("failed to check health of url %s: %v", urlInfo.ShortCode, err)
+				}
+			}
+		}()
+	}
+
+	for _, urlInfo := range dueURLs {
+		jobs <- urlInfo
+	}
+	close(jobs)
+	wg.Wait()
+
+	return nil
+}
+
+// checkURL checks a single link and records the result
+func (h *HealthChecker) checkURL(ctx context.Context, urlInfo repo.GetURLsDueForHealthCheckRow) error {
+	result := h.check(ctx, urlInfo.OriginalUrl)
+	// The destination was never requested, so there is nothing to record
+	if errors.Is(result.err, errHealthCheckAborted) {
+		return result.err
+	}
+
+	redirectChain, err := json.Marshal(result.redirectChain)
+	if err != nil {
+		return err
+	}
+	var errorMessage string
+	if result.err != nil {
+		errorMessage = result.err.Error()
+	}
+
+	healthCheck, err := h.querier.UpsertURLHealthCheck(ctx, repo.UpsertURLHealthCheckParams{
+		UrlID:         urlInfo.ID,
+		StatusCode:    int32(result.statusCode),
+		LatencyMs:     int32(result.latency.Milliseconds()),
+		RedirectChain: redirectChain,
+		Error:         errorMessage,
+		IsBroken:      result.broken(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Email the owner once the link has been broken for a while
+	if h.conf.NotifyOwners && healthCheck.IsBroken && !healthCheck.NotifiedAt.Valid &&
+		int(healthCheck.ConsecutiveFailures) >= h.conf.FailureThreshold && urlInfo.CreatedBy.Valid {
+		return h.notifyOwner(ctx, urlInfo, healthCheck)
+	}
+
+	return nil
+}
+
+// check requests the URL and follows its redirects, with HEAD first and GET as a fallback
+// The latency only counts the time spent on requests, not waiting for the rate limit of the hosts
+func (h *HealthChecker) check(ctx context.Context, rawURL string) healthCheckResult {
+	result := healthCheckResult{redirectChain: []string{rawURL}}
+
+	currentURL := rawURL
+	for range h.conf.MaxRedirects + 1 {
+		statusCode, location, latency, err := h.request(ctx, currentURL)
+		result.latency += latency
+		if err != nil {
+			result.err = err
+			return result
+		}
+		result.statusCode = statusCode
+
+		// Not a redirect, so this is the final response
+		if location == "" {
+			return result
+		}
+
+		result.redirectChain = append(result.redirectChain, location)
+		currentURL = location
+	}
+
+	result.err = fmt.Errorf("stopped after %d redirects", h.conf.MaxRedirects)
+	return result
+}
+
+// request sends a single request to the URL
+// Returns the status code, the absolute target if the response is a redirect and the time spent sending
+func (h *HealthChecker) request(ctx context.Context, rawURL string) (int, string, time.Duration, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, "", 0, err
+	}
+
+	// Wait for the rate limit of the host
+	if err := h.limiter(target.Host).Wait(ctx); err != nil {
+		return 0, "", 0, fmt.Errorf("%w: %v", errHealthCheckAborted, err)
+	}
+
+	start := time.Now()
+	resp, err := h.send(ctx, http.MethodHead, rawURL)
+	if err != nil {
+		return 0, "", time.Since(start), err
+	}
+	// Some servers do not support HEAD requests
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		resp, err = h.send(ctx, http.MethodGet, rawURL)
+		if err != nil {
+			return 0, "", time.Since(start), err
+		}
+	}
+	latency := time.Since(start)
+
+	location := resp.Header.Get("Location")
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+		return resp.StatusCode, "", latency, nil
+	}
+
+	// Locations may be relative to the current URL
+	next, err := target.Parse(location)
+	if err != nil {
+		return resp.StatusCode, "", latency, fmt.Errorf("invalid redirect location %q: %w", location, err)
+	}
+	return resp.StatusCode, next.String(), latency, nil
+}
+
+// send sends the request and discards the body
+func (h *HealthChecker) send(ctx context.Context, method string, rawURL string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.conf.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", h.conf.UserAgent)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// Only the status and headers are needed
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+// limiter returns the rate limiter of the host
+// Hosts are not rate limited if no rate is configured
+func (h *HealthChecker) limiter(host string) *rate.Limiter {
+	h.limitersMu.Lock()
+	defer h.limitersMu.Unlock()
+
+	limiter, ok := h.limiters[host]
+	if !ok {
+		limit := rate.Limit(h.conf.PerHostRate)
+		if h.conf.PerHostRate <= 0 {
+			limit = rate.Inf
+		}
+		limiter = rate.NewLimiter(limit, 1)
+		h.limiters[host] = limiter
+	}
+	return limiter
+}
+
+// notifyOwner emails the owner of the link that its destination is broken
+func (h *HealthChecker) notifyOwner(ctx context.Context, urlInfo repo.GetURLsDueForHealthCheckRow, healthCheck repo.UrlHealthCheck) error {
+	owner, err := h.querier.GetUserInfoFromUsername(ctx, urlInfo.CreatedBy.String)
+	if err != nil {
+		return err
+	}
+
+	err = h.mailer.SendTemplate(ctx, owner.Email, mailer.TemplateLinkBroken, owner.Language, mailer.LinkBrokenData{
+		Username:    owner.Username,
+		ShortCode:   urlInfo.ShortCode,
+		OriginalURL: urlInfo.OriginalUrl,
+		StatusCode:  int(healthCheck.StatusCode),
+		Error:       healthCheck.Error,
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.querier.MarkURLHealthNotified(ctx, urlInfo.ID)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+// fakeHTTPDoer answers with the status code and Location header configured for each URL
+type fakeHTTPDoer struct {
+	responses map[string]fakeResponse
+}
+
+type fakeResponse struct {
+	statusCode int
+	location   string
+}
+
+func (d *fakeHTTPDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, ok := d.responses[req.URL.String()]
+	if !ok {
+		resp = fakeResponse{statusCode: http.StatusNotFound}
+	}
+	header := http.Header{}
+	if resp.location != "" {
+		header.Set("Location", resp.location)
+	}
+	return &http.Response{StatusCode: resp.statusCode, Header: header, Body: http.NoBody}, nil
+}
+
+func TestHealthCheckerCheck(t *testing.T) {
+	client := &fakeHTTPDoer{responses: map[string]fakeResponse{
+		"https://example.com/a":    {statusCode: http.StatusMovedPermanently, location: "/b"},
+		"https://example.com/b":    {statusCode: http.StatusFound, location: "https://example.com/c"},
+		"https://example.com/c":    {statusCode: http.StatusOK},
+		"https://example.com/loop": {statusCode: http.StatusFound, location: "/loop"},
+	}}
+
+	tests := []struct {
+		name       string
+		conf       config.HealthCheckConfig
+		url        string
+		wantStatus int
+		wantChain  int
+		wantBroken bool
+	}{
+		{name: "follows redirects by default", url: "https://example.com/a", wantStatus: http.StatusOK, wantChain: 3},
+		{name: "stops after max redirects", conf: config.HealthCheckConfig{MaxRedirects: 1}, url: "https://example.com/a", wantStatus: http.StatusFound, wantChain: 3, wantBroken: true},
+		{name: "redirect loop", url: "https://example.com/loop", wantStatus: http.StatusFound, wantChain: healthCheckDefaultMaxRedirects + 2, wantBroken: true},
+		{name: "not found", url: "https://example.com/missing", wantStatus: http.StatusNotFound, wantChain: 1, wantBroken: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthChecker(nil, client, nil, tt.conf)
+			result := h.check(context.Background(), tt.url)
+			if result.statusCode != tt.wantStatus {
+				t.Errorf("statusCode = %d, want %d", result.statusCode, tt.wantStatus)
+			}
+			if len(result.redirectChain) != tt.wantChain {
+				t.Errorf("redirectChain = %v, want %d entries", result.redirectChain, tt.wantChain)
+			}
+			if result.broken() != tt.wantBroken {
+				t.Errorf("broken() = %v, want %v (err %v)", result.broken(), tt.wantBroken, result.err)
+			}
+		})
+	}
+}
+
+func TestHealthCheckerLatencyExcludesRateLimit(t *testing.T) {
+	client := &fakeHTTPDoer{responses: map[string]fakeResponse{
+		"https://example.com/a": {statusCode: http.StatusFound, location: "/b"},
+		"https://example.com/b": {statusCode: http.StatusFound, location: "/c"},
+		"https://example.com/c": {statusCode: http.StatusOK},
+	}}
+	// Three requests to the same host at 10 per second wait about 200ms for the limiter
+	h := NewHealthChecker(nil, client, nil, config.HealthCheckConfig{PerHostRate: 10})
+
+	start := time.Now()
+	result := h.check(context.Background(), "https://example.com/a")
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("check took %v, expected it to wait for the rate limit", elapsed)
+	}
+	if result.latency >= 100*time.Millisecond {
+		t.Errorf("latency = %v, want the time spent on requests only", result.latency)
+	}
+}
+
+func TestNewHealthCheckerDefaults(t *testing.T) {
+	h := NewHealthChecker(nil, nil, nil, config.HealthCheckConfig{})
+	if h.conf.RecheckAfter != healthCheckDefaultRecheckAfter {
+		t.Errorf("RecheckAfter = %v, want %v", h.conf.RecheckAfter, healthCheckDefaultRecheckAfter)
+	}
+	if h.conf.MaxRedirects != healthCheckDefaultMaxRedirects {
+		t.Errorf("MaxRedirects = %d, want %d", h.conf.MaxRedirects, healthCheckDefaultMaxRedirects)
+	}
+}
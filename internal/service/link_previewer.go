@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/linkpreview"
+	"github.com/ZureTz/shorter-url/pkg/safehttp"
+)
+
+const (
+	// Number of links waiting for their preview if no queue size is configured
+	linkPreviewQueueDefaultSize = 100
+	// Time allowed for fetching a page if no timeout is configured
+	linkPreviewDefaultTimeout = 5 * time.Second
+	// Bytes read from a page if no limit is configured, the head of most pages fits easily
+	linkPreviewDefaultMaxBodyBytes = 512 << 10
+	// Time allowed for storing the preview, separate from the fetch so that its errors are stored too
+	linkPreviewStoreTimeout = 5 * time.Second
+)
+
+// linkPreviewJob is a link waiting for its preview to be fetched
+type linkPreviewJob struct {
+	urlID  int64
+	rawURL string
+}
+
+// LinkPreviewWorker fetches the metadata of newly created links in the background
+type LinkPreviewWorker struct {
+	querier repo.Querier
+	fetcher *linkpreview.Fetcher
+	conf    config.LinkPreviewConfig
+
+	jobs chan linkPreviewJob
+	wg   sync.WaitGroup
+	// Guards sending on jobs, requests still running after Stop must not send on the closed channel
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewLinkPreviewWorker creates the worker and starts its goroutines
+func NewLinkPreviewWorker(db *sql.DB, conf config.LinkPreviewConfig) *LinkPreviewWorker {
+	queueSize := conf.QueueSize
+	if queueSize <= 0 {
+		queueSize = linkPreviewQueueDefaultSize
+	}
+	workers := max(conf.Workers, 1)
+	if conf.Timeout <= 0 {
+		conf.Timeout = linkPreviewDefaultTimeout
+	}
+	// Without a limit nothing would be read, and every preview would be empty
+	if conf.MaxBodyBytes <= 0 {
+		conf.MaxBodyBytes = linkPreviewDefaultMaxBodyBytes
+	}
+
+	w := &LinkPreviewWorker{
+		querier: repo.New(db),
+		fetcher: linkpreview.NewFetcher(
+			safehttp.NewClient(conf.Timeout, conf.AllowPrivateNetworks),
+			conf.MaxBodyBytes,
+			conf.UserAgent,
+		),
+		conf: conf,
+		jobs: make(chan linkPreviewJob, queueSize),
+	}
+
+	w.wg.Add(workers)
+	for range workers {
+		go w.work()
+	}
+
+	return w
+}
+
+// Enqueue schedules fetching the preview of the link
+// The job is dropped if the queue is full, the link is still usable without a preview
+func (w *LinkPreviewWorker) Enqueue(urlID int64, rawURL string) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		log.Printf("link preview worker is stopped, skipping url %d", urlID)
+		return
+	}
+
+	select {
+	case w.jobs <- linkPreviewJob{urlID: urlID, rawURL: rawURL}:
+	default:
+		log.Printf("link preview queue is full, skipping url %d", urlID)
+	}
+}
+
+// Stop waits for the queued previews to be fetched
+func (w *LinkPreviewWorker) Stop() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.jobs)
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}
+
+func (w *LinkPreviewWorker) work() {
+	defer w.wg.Done()
+	for job := range w.jobs {
+		if err := w.fetchPreview(context.Background(), job); err != nil {
+			log.Printf("failed to store preview of url %d: %v", job.urlID, err)
+		}
+	}
+}
+
+// fetchPreview fetches the metadata of the page and stores it
+// Fetch errors are stored too, so the owner can see why no preview is shown
+func (w *LinkPreviewWorker) fetchPreview(ctx context.Context, job linkPreviewJob) error {
+	fetchCtx, cancel := context.WithTimeout(ctx, w.conf.Timeout)
+	defer cancel()
+
+	params := repo.UpsertURLPreviewParams{UrlID: job.urlID}
+	metadata, err := w.fetcher.Fetch(fetchCtx, job.rawURL)
+	if err != nil {
+		params.FetchError = err.Error()
+	} else {
+		params.Title = metadata.Title
+		params.Description = metadata.Description
+		params.OgTitle = metadata.OGTitle
+		params.OgDescription = metadata.OGDescription
+		params.OgImage = metadata.OGImage
+		params.OgSiteName = metadata.OGSiteName
+		params.FaviconUrl = metadata.FaviconURL
+	}
+
+	// Pages timing out are the most common failure, so the fetch must not use up the time of storing it
+	storeCtx, cancel := context.WithTimeout(ctx, linkPreviewStoreTimeout)
+	defer cancel()
+	return w.querier.UpsertURLPreview(storeCtx, params)
+}
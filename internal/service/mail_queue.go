@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/mailer"
+)
+
+// Defaults of the queue settings that are not configured
+const (
+	mailQueueDefaultPollInterval = 10 * time.Second
+	mailQueueDefaultBatchSize    = 50
+	mailQueueDefaultMaxAttempts  = 8
+	mailQueueDefaultLockTimeout  = 5 * time.Minute
+	mailQueueDefaultRetryBase    = 30 * time.Second
+	mailQueueDefaultRetryMax     = time.Hour
+	// Dead letters keep the whole email, so they are not kept forever
+	mailQueueDefaultDeadLetterRetention = 30 * 24 * time.Hour
+	mailQueueDeadLetterPurgeInterval    = time.Hour
+)
+
+// MailRenderer renders the emails sent to users
+type MailRenderer interface {
+	Render(to string, template string, language string, data any) (*mailer.Message, error)
+	MatchLanguage(preferred string) string
+}
+
+// MailTransport delivers rendered emails
+type MailTransport interface {
+	Send(msg *mailer.Message) error
+	Close() error
+}
+
+// MailQueue stores outgoing emails in the database and sends them in the background
+// Failed sends are retried with exponential backoff, and moved to the dead letters after the maximum number of attempts
+type MailQueue struct {
+	querier   repo.Querier
+	renderer  MailRenderer
+	transport MailTransport
+	conf      config.MailQueueConfig
+
+	// Wakes the worker up when an email is queued
+	wake chan struct{}
+	// Receives the deadline of the drain when stopping
+	stop chan context.Context
+	done chan struct{}
+}
+
+// NewMailQueue creates the queue, its worker starts with Start
+func NewMailQueue(db *sql.DB, renderer MailRenderer, transport MailTransport, conf config.MailQueueConfig) *MailQueue {
+	if conf.PollInterval <= 0 {
+		conf.PollInterval = mailQueueDefaultPollInterval
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = mailQueueDefaultBatchSize
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = mailQueueDefaultMaxAttempts
+	}
+	// Without a lock other instances would claim the emails being sent right away
+	if conf.LockTimeout <= 0 {
+		conf.LockTimeout = mailQueueDefaultLockTimeout
+	}
+	if conf.RetryBaseDelay <= 0 {
+		conf.RetryBaseDelay = mailQueueDefaultRetryBase
+	}
+	if conf.RetryMaxDelay <= 0 {
+		conf.RetryMaxDelay = mailQueueDefaultRetryMax
+	}
+	conf.RetryMaxDelay = max(conf.RetryMaxDelay, conf.RetryBaseDelay)
+	if conf.DeadLetterRetention <= 0 {
+		conf.DeadLetterRetention = mailQueueDefaultDeadLetterRetention
+	}
+
+	return &MailQueue{
+		querier:   repo.New(db),
+		renderer:  renderer,
+		transport: transport,
+		conf:      conf,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan context.Context),
+		done:      make(chan struct{}),
+	}
+}
+
+// SendTemplate renders the template in the language and queues the email
+func (q *MailQueue) SendTemplate(ctx context.Context, to string, template string, language string, data any) error {
+	msg, err := q.renderer.Render(to, template, language, data)
+	if err != nil {
+		return err
+	}
+
+	err = q.querier.EnqueueMail(ctx, repo.EnqueueMailParams{
+		Recipient:       msg.To,
+		Subject:         msg.Subject,
+		TextBody:        msg.Text,
+		HtmlBody:        msg.HTML,
+		ListUnsubscribe: msg.ListUnsubscribe,
+		ExpiresAt:       sql.NullTime{Time: msg.ExpiresAt.UTC(), Valid: !msg.ExpiresAt.IsZero()},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Send it right away rather than on the next poll
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// MatchLanguage returns the language of the emails sent to someone preferring the languages
+func (q *MailQueue) MatchLanguage(preferred string) string {
+	return q.renderer.MatchLanguage(preferred)
+}
+
+// Start starts the worker sending the queued emails
+func (q *MailQueue) Start() {
+	go q.work()
+}
+
+// Stop sends the emails that are due until the context is done, then stops the worker
+// Emails left in the queue are sent after the next start
+func (q *MailQueue) Stop(ctx context.Context) error {
+	select {
+	case q.stop <- ctx:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *MailQueue) work() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.conf.PollInterval)
+	defer ticker.Stop()
+
+	var lastPurge time.Time
+	for {
+		if time.Since(lastPurge) >= mailQueueDeadLetterPurgeInterval {
+			q.purgeDeadLetters()
+			lastPurge = time.Now()
+		}
+
+		// Keep the connection open while emails keep coming, and close it once the queue is idle
+		if sent := q.sendDue(context.Background()); sent == 0 {
+			if err := q.transport.Close(); err != nil {
+				log.Printf("failed to close mail transport: %v", err)
+			}
+		}
+
+		select {
+		case ctx := <-q.stop:
+			// Drain the queue before stopping
+			q.sendDue(ctx)
+			if err := q.transport.Close(); err != nil {
+				log.Printf("failed to close mail transport: %v", err)
+			}
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue sends the emails that are due in batches, until none are left or the context is done
+// Returns the number of emails sent or retried
+func (q *MailQueue) sendDue(ctx context.Context) int {
+	batchSize := q.conf.BatchSize
+	sent := 0
+	for ctx.Err() == nil {
+		// Other instances skip the claimed emails until the lock times out
+		mails, err := q.querier.ClaimDueMail(ctx, repo.ClaimDueMailParams{
+			LockedUntil: sql.NullTime{Time: time.Now().UTC().Add(q.conf.LockTimeout), Valid: true},
+			Limit:       int32(batchSize),
+		})
+		if err != nil {
+			log.Printf("failed to claim queued emails: %v", err)
+			return sent
+		}
+
+		for i, mail := range mails {
+			if ctx.Err() != nil {
+				// Let the next start send the rest right away
+				for _, unsent := range mails[i:] {
+					if err := q.querier.ReleaseMail(context.Background(), unsent.ID); err != nil {
+						log.Printf("failed to release email %d: %v", unsent.ID, err)
+					}
+				}
+				return sent
+			}
+			q.send(mail)
+			sent++
+		}
+
+		if len(mails) < batchSize {
+			break
+		}
+	}
+
+	return sent
+}
+
+// send sends the email, and schedules a retry or moves it to the dead letters if it fails
+func (q *MailQueue) send(mail repo.MailQueue) {
+	// Updates of the queue must not be cancelled halfway
+	ctx := context.Background()
+
+	// Expired emails like old email codes are useless, so they are dropped rather than sent late
+	if mail.ExpiresAt.Valid && !time.Now().Before(mail.ExpiresAt.Time) {
+		log.Printf("dropping expired email %d to %s", mail.ID, mail.Recipient)
+		if err := q.querier.DeleteMail(ctx, mail.ID); err != nil {
+			log.Printf("failed to delete expired email %d: %v", mail.ID, err)
+		}
+		return
+	}
+
+	sendErr := q.transport.Send(&mailer.Message{
+		To:              mail.Recipient,
+		Subject:         mail.Subject,
+		Text:            mail.TextBody,
+		HTML:            mail.HtmlBody,
+		ListUnsubscribe: mail.ListUnsubscribe,
+	})
+	if sendErr == nil {
+		if err := q.querier.DeleteMail(ctx, mail.ID); err != nil {
+			log.Printf("failed to delete sent email %d: %v", mail.ID, err)
+		}
+		return
+	}
+
+	// Rejected recipients and emails out of attempts are not retried
+	attempts := int(mail.Attempts) + 1
+	if mailer.IsPermanent(sendErr) || attempts >= q.conf.MaxAttempts {
+		log.Printf("giving up on email %d to %s after %d attempts: %v", mail.ID, mail.Recipient, attempts, sendErr)
+		err := q.querier.MoveMailToDeadLetters(ctx, repo.MoveMailToDeadLettersParams{
+			ID:        mail.ID,
+			LastError: sendErr.Error(),
+		})
+		if err != nil {
+			log.Printf("failed to move email %d to the dead letters: %v", mail.ID, err)
+		}
+		return
+	}
+
+	nextAttemptAt := time.Now().UTC().Add(q.retryDelay(attempts))
+	if mail.ExpiresAt.Valid && !nextAttemptAt.Before(mail.ExpiresAt.Time) {
+		log.Printf("dropping email %d to %s, it expires before the next attempt: %v", mail.ID, mail.Recipient, sendErr)
+		if err := q.querier.DeleteMail(ctx, mail.ID); err != nil {
+			log.Printf("failed to delete expired email %d: %v", mail.ID, err)
+		}
+		return
+	}
+
+	err := q.querier.RetryMail(ctx, repo.RetryMailParams{
+		ID:            mail.ID,
+		LastError:     sendErr.Error(),
+		NextAttemptAt: nextAttemptAt,
+	})
+	if err != nil {
+		log.Printf("failed to schedule a retry of email %d: %v", mail.ID, err)
+	}
+}
+
+// purgeDeadLetters deletes the dead letters older than the retention
+func (q *MailQueue) purgeDeadLetters() {
+	purged, err := q.querier.DeleteOldDeadLetters(context.Background(), time.Now().UTC().Add(-q.conf.DeadLetterRetention))
+	if err != nil {
+		log.Printf("failed to purge dead letters: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("purged %d dead letters", purged)
+	}
+}
+
+// retryDelay returns the delay before the next attempt, doubling with each failed attempt
+func (q *MailQueue) retryDelay(attempts int) time.Duration {
+	delay := q.conf.RetryBaseDelay
+	for range attempts - 1 {
+		delay *= 2
+		if delay >= q.conf.RetryMaxDelay {
+			return q.conf.RetryMaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/mailer"
+)
+
+func TestMailQueueRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     config.MailQueueConfig
+		attempts int
+		want     time.Duration
+	}{
+		{name: "first attempt waits the base delay", conf: config.MailQueueConfig{RetryBaseDelay: time.Minute, RetryMaxDelay: time.Hour}, attempts: 1, want: time.Minute},
+		{name: "second attempt doubles", conf: config.MailQueueConfig{RetryBaseDelay: time.Minute, RetryMaxDelay: time.Hour}, attempts: 2, want: 2 * time.Minute},
+		{name: "fifth attempt", conf: config.MailQueueConfig{RetryBaseDelay: time.Minute, RetryMaxDelay: time.Hour}, attempts: 5, want: 16 * time.Minute},
+		{name: "capped at the maximum", conf: config.MailQueueConfig{RetryBaseDelay: time.Minute, RetryMaxDelay: time.Hour}, attempts: 7, want: time.Hour},
+		{name: "many attempts do not overflow", conf: config.MailQueueConfig{RetryBaseDelay: time.Minute, RetryMaxDelay: time.Hour}, attempts: 1000, want: time.Hour},
+		{name: "defaults", conf: config.MailQueueConfig{}, attempts: 1, want: mailQueueDefaultRetryBase},
+		{name: "default maximum", conf: config.MailQueueConfig{}, attempts: 100, want: mailQueueDefaultRetryMax},
+		{name: "maximum below the base is raised to the base", conf: config.MailQueueConfig{RetryBaseDelay: time.Hour, RetryMaxDelay: time.Minute}, attempts: 3, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewMailQueue(nil, nil, nil, tt.conf)
+			if got := q.retryDelay(tt.attempts); got != tt.want {
+				t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeMailQuerier records the updates of the queue, the other queries are not used by send
+type fakeMailQuerier struct {
+	repo.Querier
+	deleted []int64
+	retried []repo.RetryMailParams
+	dead    []repo.MoveMailToDeadLettersParams
+}
+
+func (f *fakeMailQuerier) DeleteMail(ctx context.Context, id int64) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func (f *fakeMailQuerier) RetryMail(ctx context.Context, arg repo.RetryMailParams) error {
+	f.retried = append(f.retried, arg)
+	return nil
+}
+
+func (f *fakeMailQuerier) MoveMailToDeadLetters(ctx context.Context, arg repo.MoveMailToDeadLettersParams) error {
+	f.dead = append(f.dead, arg)
+	return nil
+}
+
+// fakeMailTransport fails every send with err, if set
+type fakeMailTransport struct {
+	err  error
+	sent int
+}
+
+func (f *fakeMailTransport) Send(msg *mailer.Message) error {
+	f.sent++
+	return f.err
+}
+
+func (f *fakeMailTransport) Close() error { return nil }
+
+func TestMailQueueSendExpiry(t *testing.T) {
+	now := time.Now().UTC()
+	conf := config.MailQueueConfig{RetryBaseDelay: time.Minute, RetryMaxDelay: time.Hour}
+	tests := []struct {
+		name        string
+		expiresAt   sql.NullTime
+		sendErr     error
+		wantSent    int
+		wantDeleted int
+		wantRetried int
+	}{
+		{name: "sent before the expiry", expiresAt: sql.NullTime{Time: now.Add(5 * time.Minute), Valid: true}, wantSent: 1, wantDeleted: 1},
+		{name: "expired emails are dropped unsent", expiresAt: sql.NullTime{Time: now.Add(-time.Second), Valid: true}, wantDeleted: 1},
+		{name: "not retried past the expiry", expiresAt: sql.NullTime{Time: now.Add(30 * time.Second), Valid: true}, sendErr: errors.New("connection refused"), wantSent: 1, wantDeleted: 1},
+		{name: "retried before the expiry", expiresAt: sql.NullTime{Time: now.Add(5 * time.Minute), Valid: true}, sendErr: errors.New("connection refused"), wantSent: 1, wantRetried: 1},
+		{name: "emails without expiry are retried", sendErr: errors.New("connection refused"), wantSent: 1, wantRetried: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			querier := &fakeMailQuerier{}
+			transport := &fakeMailTransport{err: tt.sendErr}
+			q := NewMailQueue(nil, nil, transport, conf)
+			q.querier = querier
+
+			q.send(repo.MailQueue{ID: 1, Recipient: "to@example.com", ExpiresAt: tt.expiresAt})
+			if transport.sent != tt.wantSent {
+				t.Errorf("sent %d emails, want %d", transport.sent, tt.wantSent)
+			}
+			if len(querier.deleted) != tt.wantDeleted {
+				t.Errorf("deleted %d emails, want %d", len(querier.deleted), tt.wantDeleted)
+			}
+			if len(querier.retried) != tt.wantRetried {
+				t.Errorf("retried %d emails, want %d", len(querier.retried), tt.wantRetried)
+			}
+			if len(querier.dead) != 0 {
+				t.Errorf("moved %d emails to the dead letters, want none", len(querier.dead))
+			}
+		})
+	}
+}
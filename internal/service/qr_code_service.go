@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/qrcode"
+)
+
+var (
+	ErrURLNotFound = errors.New("url not found")
+	// Errors of the renderer wrap it if the options were rejected, other errors are failures of the renderer
+	ErrInvalidQRCodeOptions = qrcode.ErrInvalidOptions
+)
+
+// QRCodeRenderer renders QR code images
+type QRCodeRenderer interface {
+	Render(content string, opts qrcode.Options) ([]byte, error)
+}
+
+// QRCodeService renders the QR codes of short URLs
+type QRCodeService struct {
+	querier          repo.Querier
+	cacher           Cacher
+	renderer         QRCodeRenderer
+	shortLinkBaseURL string
+}
+
+// NewQRCodeService creates a new instance of QRCodeService with the provided dependencies
+func NewQRCodeService(db *sql.DB, cacher Cacher, renderer QRCodeRenderer, conf config.URLServiceConfig) *QRCodeService {
+	return &QRCodeService{
+		querier:          repo.New(db),
+		cacher:           cacher,
+		renderer:         renderer,
+		shortLinkBaseURL: conf.ShortLinkBaseURL,
+	}
+}
+
+// GetShortURLQRCode renders the QR code of any active short URL
+func (s *QRCodeService) GetShortURLQRCode(ctx context.Context, shortCode string, req model.QRCodeRequest) (*model.QRCodeImage, error) {
+	// Only existing short URLs get a QR code
+	cachedURL, err := s.cacher.GetURLFromCache(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if cachedURL == nil {
+		if _, err := s.querier.GetURLByShortCode(ctx, shortCode); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrURLNotFound
+			}
+			return nil, err
+		}
+	}
+
+	return s.render(ctx, shortCode, req)
+}
+
+// GetUserURLQRCode renders the QR code of one of the user's short URLs
+func (s *QRCodeService) GetUserURLQRCode(ctx context.Context, req model.QRCodeRequest, username string) (*model.QRCodeImage, error) {
+	urlInfo, err := s.querier.GetUserURLByID(ctx, repo.GetUserURLByIDParams{
+		ID: req.ID,
+		CreatedBy: sql.NullString{
+			String: username,
+			Valid:  username != "",
+		},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrURLNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.render(ctx, urlInfo.ShortCode, req)
+}
+
+// render renders the QR code of the short URL, reusing the cached image for the same options
+func (s *QRCodeService) render(ctx context.Context, shortCode string, req model.QRCodeRequest) (*model.QRCodeImage, error) {
+	content := s.shortLinkBaseURL + "/" + shortCode
+	format := req.Format
+	if format == "" {
+		format = qrcode.FormatPNG
+	}
+
+	// The image only depends on the content and the options
+	margin := "default"
+	if req.Margin != nil {
+		margin = fmt.Sprint(*req.Margin)
+	}
+	hash := sha256.Sum256(fmt.Appendf(nil, "%s|%d|%s|%s|%s|%s|%s|%t",
+		content, req.Size, req.Level, req.Foreground, req.Background, margin, format, req.Logo))
+	key := hex.EncodeToString(hash[:])
+
+	image, err := s.cacher.GetQRCode(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if image == nil {
+		image, err = s.renderer.Render(content, qrcode.Options{
+			Size:       req.Size,
+			Level:      req.Level,
+			Foreground: req.Foreground,
+			Background: req.Background,
+			Margin:     req.Margin,
+			Format:     format,
+			Logo:       req.Logo,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Failing to cache the image does not fail the request
+		if err := s.cacher.StoreQRCode(ctx, key, image); err != nil {
+			log.Printf("failed to cache qr code of %s: %v", shortCode, err)
+		}
+	}
+
+	return &model.QRCodeImage{
+		Data:        image,
+		ContentType: qrcode.ContentType(format),
+	}, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/model"
+)
+
+// Route of the policy applying to every route without one
+const rateLimitFallbackRoute = "*"
+
+// RateLimiter throttles requests with the budgets of the policies of their routes
+type RateLimiter struct {
+	cacher Cacher
+	// Policies keyed by "METHOD /path"
+	policies map[string]config.RateLimitPolicy
+	apiKeys  map[string]struct{}
+	failOpen bool
+}
+
+// NewRateLimiter creates a new instance of RateLimiter with the policies of the config
+func NewRateLimiter(cacher Cacher, c config.RateLimitConfig) (*RateLimiter, error) {
+	policies := make(map[string]config.RateLimitPolicy)
+	for _, policy := range c.Policies {
+		if policy.Window <= 0 {
+			return nil, fmt.Errorf("rate limit policy %q needs a positive window", policy.Name)
+		}
+		for _, route := range policy.Routes {
+			if other, ok := policies[route]; ok {
+				return nil, fmt.Errorf("route %q is in rate limit policies %q and %q", route, other.Name, policy.Name)
+			}
+			policies[route] = policy
+		}
+	}
+
+	apiKeys := make(map[string]struct{}, len(c.APIKeys))
+	for _, key := range c.APIKeys {
+		apiKeys[key] = struct{}{}
+	}
+
+	return &RateLimiter{
+		cacher:   cacher,
+		policies: policies,
+		apiKeys:  apiKeys,
+		failOpen: c.FailOpen,
+	}, nil
+}
+
+// Allow takes a request of the client to the route from its budget
+// Returns nil if the route is not limited for the client
+func (l *RateLimiter) Allow(ctx context.Context, method string, path string, client model.RateLimitClient) (*model.RateLimitResult, error) {
+	policy, ok := l.policies[method+" "+path]
+	if !ok {
+		policy, ok = l.policies[rateLimitFallbackRoute]
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	// Trusted API keys come first, then logged in users, everyone else shares the budget of their address
+	var kind, id string
+	var limit int
+	if _, trusted := l.apiKeys[client.APIKey]; trusted && client.APIKey != "" {
+		// Keep the keys themselves out of redis
+		hash := sha256.Sum256([]byte(client.APIKey))
+		kind, id, limit = model.RateLimitAPIKey, hex.EncodeToString(hash[:8]), policy.APIKey
+	} else if client.UserID != "" {
+		kind, id, limit = model.RateLimitAuthenticated, client.UserID, policy.Authenticated
+	} else {
+		kind, id, limit = model.RateLimitAnonymous, client.IP, policy.Anonymous
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	result, err := l.cacher.TakeRateLimitToken(ctx, policy.Name+":"+kind+":"+id, limit, policy.Window)
+	if err != nil {
+		if l.failOpen {
+			log.Printf("failed to check the rate limit of %s %s, letting the request through: %v", kind, id, err)
+			return nil, nil
+		}
+		return nil, err
+	}
+	result.Policy = policy.Name
+
+	return result, nil
+}
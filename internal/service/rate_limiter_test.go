@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/model"
+)
+
+// fakeRateLimitCacher records the buckets tokens are taken from, the other methods are not used by the rate limiter
+type fakeRateLimitCacher struct {
+	Cacher
+	err    error
+	key    string
+	limit  int
+	window time.Duration
+}
+
+func (f *fakeRateLimitCacher) TakeRateLimitToken(ctx context.Context, key string, limit int, window time.Duration) (*model.RateLimitResult, error) {
+	f.key, f.limit, f.window = key, limit, window
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &model.RateLimitResult{Allowed: true, Limit: limit, Window: window, Remaining: limit - 1}, nil
+}
+
+var testRateLimitConfig = config.RateLimitConfig{
+	APIKeys: []string{"trusted-key"},
+	Policies: []config.RateLimitPolicy{
+		{Name: "auth", Routes: []string{"POST /api/login"}, Anonymous: 5, Authenticated: 10, APIKey: 50, Window: time.Minute},
+		{Name: "redirect", Routes: []string{"GET /:short_code"}, Anonymous: 0, Authenticated: 600, APIKey: 3000, Window: time.Minute},
+		{Name: "default", Routes: []string{"*"}, Anonymous: 60, Authenticated: 300, APIKey: 1200, Window: time.Hour},
+	},
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		client     model.RateLimitClient
+		wantKey    string
+		wantLimit  int
+		wantWindow time.Duration
+	}{
+		{name: "anonymous by address", method: "POST", path: "/api/login", client: model.RateLimitClient{IP: "203.0.113.7"},
+			wantKey: "auth:anonymous:203.0.113.7", wantLimit: 5, wantWindow: time.Minute},
+		{name: "logged in users by ID", method: "POST", path: "/api/login", client: model.RateLimitClient{IP: "203.0.113.7", UserID: "user-1"},
+			wantKey: "auth:user:user-1", wantLimit: 10, wantWindow: time.Minute},
+		{name: "trusted API keys come first", method: "POST", path: "/api/login", client: model.RateLimitClient{IP: "203.0.113.7", UserID: "user-1", APIKey: "trusted-key"},
+			wantKey: "auth:api_key:", wantLimit: 50, wantWindow: time.Minute},
+		{name: "unknown API keys are ignored", method: "POST", path: "/api/login", client: model.RateLimitClient{IP: "203.0.113.7", APIKey: "guessed-key"},
+			wantKey: "auth:anonymous:203.0.113.7", wantLimit: 5, wantWindow: time.Minute},
+		{name: "routes without a policy use the fallback", method: "GET", path: "/api/user/me", client: model.RateLimitClient{IP: "203.0.113.7"},
+			wantKey: "default:anonymous:203.0.113.7", wantLimit: 60, wantWindow: time.Hour},
+		{name: "other methods of a route use the fallback", method: "GET", path: "/api/login", client: model.RateLimitClient{UserID: "user-1"},
+			wantKey: "default:user:user-1", wantLimit: 300, wantWindow: time.Hour},
+		{name: "unlimited for the client", method: "GET", path: "/:short_code", client: model.RateLimitClient{IP: "203.0.113.7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacher := &fakeRateLimitCacher{}
+			limiter, err := NewRateLimiter(cacher, testRateLimitConfig)
+			if err != nil {
+				t.Fatalf("NewRateLimiter() error = %v", err)
+			}
+
+			result, err := limiter.Allow(context.Background(), tt.method, tt.path, tt.client)
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if tt.wantKey == "" {
+				if result != nil || cacher.key != "" {
+					t.Errorf("Allow() = %+v, took a token from %q, want no limit", result, cacher.key)
+				}
+				return
+			}
+
+			if !strings.HasPrefix(cacher.key, tt.wantKey) || cacher.limit != tt.wantLimit || cacher.window != tt.wantWindow {
+				t.Errorf("took a token from %q with limit %d per %v, want %q with %d per %v",
+					cacher.key, cacher.limit, cacher.window, tt.wantKey, tt.wantLimit, tt.wantWindow)
+			}
+			if strings.Contains(cacher.key, tt.client.APIKey) && tt.client.APIKey != "" {
+				t.Errorf("key %q contains the API key", cacher.key)
+			}
+			if result.Policy != strings.SplitN(tt.wantKey, ":", 2)[0] {
+				t.Errorf("Policy = %q, want the policy of the route", result.Policy)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRedisErrors(t *testing.T) {
+	client := model.RateLimitClient{IP: "203.0.113.7"}
+
+	for _, failOpen := range []bool{true, false} {
+		conf := testRateLimitConfig
+		conf.FailOpen = failOpen
+		limiter, err := NewRateLimiter(&fakeRateLimitCacher{err: errors.New("connection refused")}, conf)
+		if err != nil {
+			t.Fatalf("NewRateLimiter() error = %v", err)
+		}
+
+		result, err := limiter.Allow(context.Background(), "POST", "/api/login", client)
+		if failOpen && (err != nil || result != nil) {
+			t.Errorf("fail open: Allow() = %+v, %v, want the request let through", result, err)
+		}
+		if !failOpen && err == nil {
+			t.Errorf("fail closed: Allow() = %+v, want an error", result)
+		}
+	}
+}
+
+func TestNewRateLimiterInvalidPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []config.RateLimitPolicy
+	}{
+		{name: "no window", policies: []config.RateLimitPolicy{{Name: "a", Routes: []string{"*"}, Anonymous: 1}}},
+		{name: "route in two policies", policies: []config.RateLimitPolicy{
+			{Name: "a", Routes: []string{"GET /a"}, Window: time.Minute},
+			{Name: "b", Routes: []string{"GET /b", "GET /a"}, Window: time.Minute},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRateLimiter(nil, config.RateLimitConfig{Policies: tt.policies}); err == nil {
+				t.Error("NewRateLimiter() error = nil, want an error")
+			}
+		})
+	}
+}
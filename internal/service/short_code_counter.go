@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ZureTz/shorter-url/internal/repo"
+)
+
+// ShortCodeCounter hands out the numbers of the counter short code strategy from a Postgres sequence
+// A counter in the cache would start over after a flush or a failover, and hand out used codes again
+type ShortCodeCounter struct {
+	querier repo.Querier
+}
+
+// NewShortCodeCounter creates a counter backed by the short_code_counter sequence
+func NewShortCodeCounter(db *sql.DB) *ShortCodeCounter {
+	return &ShortCodeCounter{querier: repo.New(db)}
+}
+
+// Next returns the next number of the sequence
+func (c *ShortCodeCounter) Next(ctx context.Context) (uint64, error) {
+	n, err := c.querier.NextShortCodeNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/repo"
+)
+
+// Metrics of the short code pool, served at /debug/vars when enabled
+var (
+	poolSizeMetric          = expvar.NewInt("short_code_pool_size")
+	poolLowWaterMetric      = expvar.NewInt("short_code_pool_low_water_events")
+	poolEmptyMetric         = expvar.NewInt("short_code_pool_empty_takes")
+	poolRefilledCodesMetric = expvar.NewInt("short_code_pool_refilled_codes")
+)
+
+// Number of consecutive already used codes after which a refill gives up
+const maxPoolRefillMisses = 100
+
+// Defaults of the pool settings that are not configured
+const (
+	poolDefaultLowWaterMark   = 1000
+	poolDefaultTargetSize     = 10000
+	poolDefaultRefillInterval = time.Minute
+)
+
+// ShortCodePool hands out short codes that were pre-generated into the database
+type ShortCodePool struct {
+	querier        repo.Querier
+	codeGenerator  CodeGenerator
+	codeFilter     CodeFilter
+	lowWaterMark   int64
+	targetSize     int64
+	refillInterval time.Duration
+}
+
+// NewShortCodePool creates a pool filled with codes from the given generator
+func NewShortCodePool(db *sql.DB, codeGenerator CodeGenerator, codeFilter CodeFilter, conf config.ShortCodePoolConfig) *ShortCodePool {
+	if conf.LowWaterMark <= 0 {
+		conf.LowWaterMark = poolDefaultLowWaterMark
+	}
+	if conf.TargetSize <= 0 {
+		conf.TargetSize = poolDefaultTargetSize
+	}
+	if conf.RefillInterval <= 0 {
+		conf.RefillInterval = poolDefaultRefillInterval
+	}
+
+	return &ShortCodePool{
+		querier:        repo.New(db),
+		codeGenerator:  codeGenerator,
+		codeFilter:     codeFilter,
+		lowWaterMark:   int64(conf.LowWaterMark),
+		targetSize:     int64(max(conf.TargetSize, conf.LowWaterMark)),
+		refillInterval: conf.RefillInterval,
+	}
+}
+
+// RefillInterval returns how often the pool is checked against the low water mark
+func (p *ShortCodePool) RefillInterval() time.Duration {
+	return p.refillInterval
+}
+
+// TakeShortCode atomically removes a code from the pool and returns it
+// Codes are removed from the pool when they are used otherwise, so the take needs no collision check
+// An empty string is returned if the pool is empty
+func (p *ShortCodePool) TakeShortCode(ctx context.Context) (string, error) {
+	code, err := p.querier.TakePooledShortCode(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		poolEmptyMetric.Add(1)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	poolSizeMetric.Add(-1)
+	return code, nil
+}
+
+// Refill tops the pool up to the target size once it has dropped below the low-water mark
+func (p *ShortCodePool) Refill(ctx context.Context) error {
+	// Codes used without going through the pool, e.g. by a create racing the removal, must not be handed out or counted
+	removed, err := p.querier.DeleteUsedPooledShortCodes(ctx)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		log.Printf("Removed %d used short codes from the pool", removed)
+	}
+
+	size, err := p.querier.CountPooledShortCodes(ctx)
+	if err != nil {
+		return err
+	}
+	poolSizeMetric.Set(size)
+
+	// Still enough codes left
+	if size >= p.lowWaterMark {
+		return nil
+	}
+
+	log.Printf("Short code pool is running low with %d codes left, refilling to %d", size, p.targetSize)
+	poolLowWaterMetric.Add(1)
+
+	misses := 0
+	for size < p.targetSize {
+		code, err := p.codeGenerator.GenerateShortCode(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Reserved and offensive codes never make it into the pool
+		var added int64
+		if p.codeFilter.Check(code) == nil {
+			// Codes that are already used or pooled are skipped by the insert
+			added, err = p.querier.AddShortCodeToPool(ctx, code)
+			if err != nil {
+				return err
+			}
+		}
+		if added == 0 {
+			misses++
+			if misses >= maxPoolRefillMisses {
+				return fmt.Errorf("failed to find usable short codes after %d attempts, the keyspace may be running out", misses)
+			}
+			continue
+		}
+
+		misses = 0
+		size += added
+		poolSizeMetric.Set(size)
+		poolRefilledCodesMetric.Add(added)
+	}
+
+	return nil
+}
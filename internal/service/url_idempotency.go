@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+)
+
+var (
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+	ErrRequestInProgress    = errors.New("a request with the same idempotency key is still in progress")
+	// The earlier request with the key failed while this one was waiting, it can be sent again
+	ErrIdempotentRequestFailed = errors.New("a request with the same idempotency key failed, please retry")
+)
+
+// How many times the key is reserved again when the earlier request released it in the meantime
+const idempotencyReserveAttempts = 3
+
+var errIdempotencyKeyReleased = errors.New("idempotency key was released")
+
+// idempotencyRecord is stored for every idempotency key
+// The response is nil while the first request is still being processed
+type idempotencyRecord struct {
+	Fingerprint string                        `json:"fingerprint"`
+	Response    *model.CreateShortURLResponse `json:"response,omitempty"`
+}
+
+// createShortURLIdempotent creates the short URL at most once per user and idempotency key
+func (s *URLService) createShortURLIdempotent(ctx context.Context, req model.CreateShortURLRequest) (*model.CreateShortURLResponse, error) {
+	// Keys are scoped per user, so users cannot read each other's results
+	// CreatedBy is the username of the JWT, set by the handler
+	key := req.CreatedBy + ":" + req.IdempotencyKey
+
+	fingerprint, err := requestFingerprint(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reserve the key, so that concurrent retries do not create the URL twice
+	pendingRecord, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		reserved, err := s.cacher.ReserveIdempotencyKey(ctx, key, pendingRecord)
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			break
+		}
+
+		// The key was used before, replay the stored result
+		resp, err := s.replayIdempotentRequest(ctx, key, fingerprint)
+		// The earlier request failed and released the key in the meantime, so try to take it over
+		if errors.Is(err, errIdempotencyKeyReleased) {
+			if attempt < idempotencyReserveAttempts {
+				continue
+			}
+			return nil, ErrIdempotentRequestFailed
+		}
+		return resp, err
+	}
+
+	resp, err := s.createShortURL(ctx, req)
+	if err != nil {
+		// Release the key so that the request can be retried
+		if err := s.cacher.DeleteIdempotencyRecord(ctx, key); err != nil {
+			log.Printf("failed to release idempotency key: %v", err)
+		}
+		return nil, err
+	}
+
+	// Keep the result for the idempotency window
+	record, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint, Response: resp})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cacher.StoreIdempotencyRecord(ctx, key, record); err != nil {
+		// The URL was created, so the request itself succeeded
+		log.Printf("failed to store idempotency record: %v", err)
+	}
+
+	return resp, nil
+}
+
+// replayIdempotentRequest returns the stored result of an earlier request with the same key
+func (s *URLService) replayIdempotentRequest(ctx context.Context, key string, fingerprint string) (*model.CreateShortURLResponse, error) {
+	stored, err := s.cacher.GetIdempotencyRecord(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// The earlier request failed and released the key in the meantime
+	if stored == nil {
+		return nil, errIdempotencyKeyReleased
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(stored, &record); err != nil {
+		return nil, err
+	}
+
+	// The same key must not be used for a different request
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if record.Response == nil {
+		return nil, ErrRequestInProgress
+	}
+
+	return record.Response, nil
+}
+
+// requestFingerprint hashes the request, so that a key reused for another request can be detected
+func requestFingerprint(req model.CreateShortURLRequest) (string, error) {
+	req.IdempotencyKey = ""
+	content, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
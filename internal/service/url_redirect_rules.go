@@ -0,0 +1,147 @@
+package service
+
+import (
+	"encoding/json"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/geoip"
+	"github.com/ZureTz/shorter-url/pkg/visitor"
+)
+
+// Matches every member state of the European Union in country conditions
+const europeanUnionCode = "EU"
+
+// GeoLocator finds the country of visitors for redirect rules
+type GeoLocator interface {
+	LookupCountry(ip net.IP) (geoip.Country, error)
+}
+
+// encodeRedirectRules normalizes the rules of a new URL and encodes them for the database
+func encodeRedirectRules(rules []model.RedirectRule) (json.RawMessage, error) {
+	if rules == nil {
+		rules = []model.RedirectRule{}
+	}
+	for i := range rules {
+		for j, country := range rules[i].Countries {
+			rules[i].Countries[j] = strings.ToUpper(country)
+		}
+		for j, language := range rules[i].Languages {
+			rules[i].Languages[j] = strings.ToLower(language)
+		}
+	}
+	return json.Marshal(rules)
+}
+
+// visitorTraits holds what the rules match on, each trait is only looked up when a rule needs it
+type visitorTraits struct {
+	visitor    model.Visitor
+	geoLocator GeoLocator
+
+	country   *geoip.Country
+	device    *visitor.Device
+	languages []string
+}
+
+func (t *visitorTraits) getCountry() geoip.Country {
+	if t.country == nil {
+		t.country = &geoip.Country{}
+		if ip := net.ParseIP(t.visitor.IP); ip != nil && t.geoLocator != nil {
+			if country, err := t.geoLocator.LookupCountry(ip); err == nil {
+				t.country = &country
+			}
+		}
+	}
+	return *t.country
+}
+
+func (t *visitorTraits) getDevice() visitor.Device {
+	if t.device == nil {
+		device := visitor.ParseUserAgent(t.visitor.UserAgent)
+		t.device = &device
+	}
+	return *t.device
+}
+
+// preferredLanguage returns the language the visitor prefers most, empty if none was sent
+func (t *visitorTraits) preferredLanguage() string {
+	if t.languages == nil {
+		t.languages = visitor.ParseAcceptLanguage(t.visitor.AcceptLanguage)
+	}
+	if len(t.languages) == 0 {
+		return ""
+	}
+	return t.languages[0]
+}
+
+// matchRedirectRules returns the destination of the first rule matching the visitor
+// Returns an empty string if none of the rules match
+func (t *visitorTraits) matchRedirectRules(rules []model.RedirectRule, now time.Time) string {
+	for _, rule := range rules {
+		if t.match(rule, now) {
+			return rule.Destination
+		}
+	}
+	return ""
+}
+
+// deepLink returns the link opening the app of the URL on the device of the visitor
+// Returns an empty string on other platforms or if the URL has no deep link for the platform
+func (t *visitorTraits) deepLink(urlInfo *repo.Url) string {
+	if urlInfo.IosDeepLink == "" && urlInfo.AndroidDeepLink == "" {
+		return ""
+	}
+	switch t.getDevice().OS {
+	case visitor.OSIOS:
+		return urlInfo.IosDeepLink
+	case visitor.OSAndroid:
+		return urlInfo.AndroidDeepLink
+	default:
+		return ""
+	}
+}
+
+// match reports whether the visitor meets all conditions of the rule
+func (t *visitorTraits) match(rule model.RedirectRule, now time.Time) bool {
+	if rule.ActiveFrom != nil && now.Before(*rule.ActiveFrom) {
+		return false
+	}
+	if rule.ActiveUntil != nil && !now.Before(*rule.ActiveUntil) {
+		return false
+	}
+
+	if len(rule.Countries) > 0 {
+		country := t.getCountry()
+		if !slices.ContainsFunc(rule.Countries, func(code string) bool {
+			if code == europeanUnionCode {
+				return country.IsInEuropeanUnion
+			}
+			return country.ISOCode != "" && code == country.ISOCode
+		}) {
+			return false
+		}
+	}
+
+	if len(rule.OS) > 0 && !slices.Contains(rule.OS, t.getDevice().OS) {
+		return false
+	}
+
+	if len(rule.Devices) > 0 && !slices.Contains(rule.Devices, t.getDevice().Kind) {
+		return false
+	}
+
+	if len(rule.Languages) > 0 {
+		language := t.preferredLanguage()
+		if language == "" || !slices.ContainsFunc(rule.Languages, func(languageRange string) bool {
+			return visitor.MatchLanguage(language, languageRange)
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/geoip"
+)
+
+const (
+	iPhoneUserAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	androidUserAgent = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36"
+	windowsUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+// fakeGeoLocator looks up countries from a fixed table
+type fakeGeoLocator map[string]geoip.Country
+
+func (l fakeGeoLocator) LookupCountry(ip net.IP) (geoip.Country, error) {
+	country, ok := l[ip.String()]
+	if !ok {
+		return geoip.Country{}, errors.New("address not found")
+	}
+	return country, nil
+}
+
+func TestMatchRedirectRules(t *testing.T) {
+	geoLocator := fakeGeoLocator{
+		"203.0.113.1": {ISOCode: "DE", IsInEuropeanUnion: true},
+		"203.0.113.2": {ISOCode: "CH"},
+		"203.0.113.3": {ISOCode: "US"},
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		rules   []model.RedirectRule
+		visitor model.Visitor
+		want    string
+	}{
+		{
+			name:  "no rules",
+			rules: nil,
+			want:  "",
+		},
+		{
+			name:    "country matches",
+			rules:   []model.RedirectRule{{Countries: []string{"US", "DE"}, Destination: "https://de.example.com/"}},
+			visitor: model.Visitor{IP: "203.0.113.1"},
+			want:    "https://de.example.com/",
+		},
+		{
+			name:    "country does not match",
+			rules:   []model.RedirectRule{{Countries: []string{"FR"}, Destination: "https://fr.example.com/"}},
+			visitor: model.Visitor{IP: "203.0.113.1"},
+			want:    "",
+		},
+		{
+			name:    "european union matches member states",
+			rules:   []model.RedirectRule{{Countries: []string{"EU"}, Destination: "https://eu.example.com/"}},
+			visitor: model.Visitor{IP: "203.0.113.1"},
+			want:    "https://eu.example.com/",
+		},
+		{
+			name:    "european union does not match other european countries",
+			rules:   []model.RedirectRule{{Countries: []string{"EU"}, Destination: "https://eu.example.com/"}},
+			visitor: model.Visitor{IP: "203.0.113.2"},
+			want:    "",
+		},
+		{
+			name:    "unknown address matches no country",
+			rules:   []model.RedirectRule{{Countries: []string{"US"}, Destination: "https://us.example.com/"}},
+			visitor: model.Visitor{IP: "198.51.100.1"},
+			want:    "",
+		},
+		{
+			name:    "invalid address matches no country",
+			rules:   []model.RedirectRule{{Countries: []string{"US"}, Destination: "https://us.example.com/"}},
+			visitor: model.Visitor{IP: "not an ip"},
+			want:    "",
+		},
+		{
+			name:    "operating system matches",
+			rules:   []model.RedirectRule{{OS: []string{"android", "ios"}, Destination: "https://m.example.com/"}},
+			visitor: model.Visitor{UserAgent: iPhoneUserAgent},
+			want:    "https://m.example.com/",
+		},
+		{
+			name:    "device kind does not match",
+			rules:   []model.RedirectRule{{Devices: []string{"mobile"}, Destination: "https://m.example.com/"}},
+			visitor: model.Visitor{UserAgent: windowsUserAgent},
+			want:    "",
+		},
+		{
+			name:    "language range matches the preferred language",
+			rules:   []model.RedirectRule{{Languages: []string{"pt"}, Destination: "https://pt.example.com/"}},
+			visitor: model.Visitor{AcceptLanguage: "en;q=0.5, pt-BR"},
+			want:    "https://pt.example.com/",
+		},
+		{
+			name:    "only the preferred language is matched",
+			rules:   []model.RedirectRule{{Languages: []string{"en"}, Destination: "https://en.example.com/"}},
+			visitor: model.Visitor{AcceptLanguage: "en;q=0.5, pt-BR"},
+			want:    "",
+		},
+		{
+			name:    "missing language matches no language rule",
+			rules:   []model.RedirectRule{{Languages: []string{"en"}, Destination: "https://en.example.com/"}},
+			visitor: model.Visitor{},
+			want:    "",
+		},
+		{
+			name: "all conditions must match",
+			rules: []model.RedirectRule{{
+				Countries:   []string{"DE"},
+				OS:          []string{"android"},
+				Destination: "https://de-android.example.com/",
+			}},
+			visitor: model.Visitor{IP: "203.0.113.1", UserAgent: iPhoneUserAgent},
+			want:    "",
+		},
+		{
+			name: "first matching rule wins",
+			rules: []model.RedirectRule{
+				{Countries: []string{"US"}, Destination: "https://us.example.com/"},
+				{OS: []string{"android"}, Destination: "https://android.example.com/"},
+				{Destination: "https://fallback.example.com/"},
+			},
+			visitor: model.Visitor{IP: "203.0.113.1", UserAgent: androidUserAgent},
+			want:    "https://android.example.com/",
+		},
+		{
+			name:  "active window contains now",
+			rules: []model.RedirectRule{{ActiveFrom: &before, ActiveUntil: &after, Destination: "https://sale.example.com/"}},
+			want:  "https://sale.example.com/",
+		},
+		{
+			name:  "rule is not active yet",
+			rules: []model.RedirectRule{{ActiveFrom: &after, Destination: "https://sale.example.com/"}},
+			want:  "",
+		},
+		{
+			name:  "rule is no longer active",
+			rules: []model.RedirectRule{{ActiveUntil: &now, Destination: "https://sale.example.com/"}},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traits := &visitorTraits{visitor: tt.visitor, geoLocator: geoLocator}
+			if got := traits.matchRedirectRules(tt.rules, now); got != tt.want {
+				t.Errorf("matchRedirectRules() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRedirectRulesWithoutGeoLocator(t *testing.T) {
+	traits := &visitorTraits{visitor: model.Visitor{IP: "203.0.113.1"}}
+	rules := []model.RedirectRule{{Countries: []string{"DE"}, Destination: "https://de.example.com/"}}
+	if got := traits.matchRedirectRules(rules, time.Now()); got != "" {
+		t.Errorf("matchRedirectRules() = %q, want no match", got)
+	}
+}
+
+func TestDeepLink(t *testing.T) {
+	urlInfo := &repo.Url{
+		IosDeepLink:     "myapp://ios",
+		AndroidDeepLink: "myapp://android",
+	}
+
+	tests := []struct {
+		name      string
+		userAgent string
+		urlInfo   *repo.Url
+		want      string
+	}{
+		{name: "ios", userAgent: iPhoneUserAgent, urlInfo: urlInfo, want: "myapp://ios"},
+		{name: "android", userAgent: androidUserAgent, urlInfo: urlInfo, want: "myapp://android"},
+		{name: "desktop", userAgent: windowsUserAgent, urlInfo: urlInfo, want: ""},
+		{name: "no deep link for the platform", userAgent: iPhoneUserAgent, urlInfo: &repo.Url{AndroidDeepLink: "myapp://android"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traits := &visitorTraits{visitor: model.Visitor{UserAgent: tt.userAgent}}
+			if got := traits.deepLink(tt.urlInfo); got != tt.want {
+				t.Errorf("deepLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
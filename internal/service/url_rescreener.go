@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+)
+
+// Defaults of the re-screening settings that are not configured
+const (
+	rescreenDefaultInterval  = time.Hour
+	rescreenDefaultAfter     = 24 * time.Hour
+	rescreenDefaultBatchSize = 500
+)
+
+// URLRescreener periodically screens the destinations of active links again
+// Destinations may turn malicious after the link was created, this keeps the lookups out of the redirects
+type URLRescreener struct {
+	querier     repo.Querier
+	cacher      Cacher
+	urlScreener URLScreener
+	conf        config.ScreenerConfig
+}
+
+// NewURLRescreener creates a rescreener quarantining the links the screener flags
+func NewURLRescreener(db *sql.DB, cacher Cacher, urlScreener URLScreener, conf config.ScreenerConfig) *URLRescreener {
+	if conf.RescreenInterval <= 0 {
+		conf.RescreenInterval = rescreenDefaultInterval
+	}
+	if conf.RescreenAfter <= 0 {
+		conf.RescreenAfter = rescreenDefaultAfter
+	}
+	if conf.RescreenBatchSize <= 0 {
+		conf.RescreenBatchSize = rescreenDefaultBatchSize
+	}
+
+	return &URLRescreener{
+		querier:     repo.New(db),
+		cacher:      cacher,
+		urlScreener: urlScreener,
+		conf:        conf,
+	}
+}
+
+// Interval returns how often the links due to be screened are screened
+func (r *URLRescreener) Interval() time.Duration {
+	return r.conf.RescreenInterval
+}
+
+// RescreenDueURLs screens the links that were not screened recently
+func (r *URLRescreener) RescreenDueURLs(ctx context.Context) error {
+	dueURLs, err := r.querier.GetURLsDueForScreening(ctx, repo.GetURLsDueForScreeningParams{
+		ScreenedBefore: time.Now().UTC().Add(-r.conf.RescreenAfter),
+		MaxUrls:        int32(r.conf.RescreenBatchSize),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, urlInfo := range dueURLs {
+		if err := r.rescreenURL(ctx, urlInfo); err != nil {
+			log.Printf("failed to screen url %s: %v", urlInfo.ShortCode, err)
+		}
+	}
+	return nil
+}
+
+// rescreenURL screens every destination the link can send visitors to, and quarantines it if one is flagged
+func (r *URLRescreener) rescreenURL(ctx context.Context, urlInfo repo.GetURLsDueForScreeningRow) error {
+	var rules []model.RedirectRule
+	if len(urlInfo.RedirectRules) > 0 {
+		if err := json.Unmarshal(urlInfo.RedirectRules, &rules); err != nil {
+			return fmt.Errorf("failed to decode redirect rules: %w", err)
+		}
+	}
+	variants, err := r.querier.GetURLVariants(ctx, urlInfo.ID)
+	if err != nil {
+		return err
+	}
+	var variantDestinations []string
+	for _, variant := range variants {
+		variantDestinations = append(variantDestinations, variant.Destination)
+	}
+
+	destinations := linkDestinations(urlInfo.OriginalUrl, variantDestinations, rules, urlInfo.IosDeepLink, urlInfo.AndroidDeepLink)
+	reason, err := screenDestinations(ctx, r.urlScreener, destinations)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		if err := r.querier.QuarantineURL(ctx, repo.QuarantineURLParams{
+			ID:               urlInfo.ID,
+			QuarantineReason: reason,
+		}); err != nil {
+			return err
+		}
+		// The cached copy is not quarantined yet, the next redirect loads the quarantined one
+		if err := r.cacher.DeleteURLFromCache(ctx, urlInfo.ShortCode); err != nil {
+			return err
+		}
+	}
+
+	return r.querier.MarkURLScreened(ctx, urlInfo.ID)
+}
+
+// linkDestinations returns every destination a link can send visitors to
+func linkDestinations(originalURL string, variantDestinations []string, rules []model.RedirectRule, iosDeepLink string, androidDeepLink string) []string {
+	destinations := []string{originalURL}
+	destinations = append(destinations, variantDestinations...)
+	for _, rule := range rules {
+		destinations = append(destinations, rule.Destination)
+	}
+	for _, deepLink := range []string{iosDeepLink, androidDeepLink} {
+		if deepLink != "" {
+			destinations = append(destinations, deepLink)
+		}
+	}
+	return destinations
+}
+
+// screenDestinations returns the reason the first flagged destination was flagged, or an empty string if all are clean
+func screenDestinations(ctx context.Context, urlScreener URLScreener, destinations []string) (string, error) {
+	for _, destination := range destinations {
+		reason, err := urlScreener.Screen(ctx, destination)
+		if err != nil {
+			return "", fmt.Errorf("failed to screen url: %w", err)
+		}
+		if reason != "" {
+			return reason, nil
+		}
+	}
+	return "", nil
+}
@@ -3,8 +3,11 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"net/url"
 	"time"
 
 	"github.com/ZureTz/shorter-url/config"
@@ -13,35 +16,144 @@ import (
 )
 
 type CodeGenerator interface {
-	GenerateShortCode() string
+	GenerateShortCode(ctx context.Context) (string, error)
+}
+
+// CodeFilter rejects short codes that are reserved or offensive
+type CodeFilter interface {
+	Check(code string) error
+}
+
+// URLNormalizer turns URLs into their canonical form
+type URLNormalizer interface {
+	Normalize(rawURL string) (string, error)
+}
+
+// URLScreener flags malicious or phishing destinations
+// Screen returns the reason the URL was flagged, or an empty string if it is clean
+type URLScreener interface {
+	Screen(ctx context.Context, rawURL string) (string, error)
+}
+
+// LinkPreviewer fetches the title, description and Open Graph tags of new links in the background
+type LinkPreviewer interface {
+	Enqueue(urlID int64, rawURL string)
+}
+
+// CodePool hands out pre-generated short codes that are known to be unused
+type CodePool interface {
+	TakeShortCode(ctx context.Context) (string, error)
 }
 
 type URLService struct {
-	querier           repo.Querier
-	cacher            Cacher
-	codeGenerator     CodeGenerator
-	defaultExpiration time.Duration
-	ShortLinkBaseURL  string
+	db                 *sql.DB
+	querier            repo.Querier
+	cacher             Cacher
+	codeGenerator      CodeGenerator
+	codeFilter         CodeFilter
+	codePool           CodePool
+	urlNormalizer      URLNormalizer
+	urlScreener        URLScreener
+	linkPreviewer      LinkPreviewer
+	geoLocator         GeoLocator
+	defaultExpiration  time.Duration
+	clickFlushInterval time.Duration
+	ShortLinkBaseURL   string
+	warmupConf         config.CacheWarmupConfig
 }
 
 // NewURLService creates a new instance of URLService with the provided dependencies
-func NewURLService(db *sql.DB, cacher Cacher, codeGenerator CodeGenerator, conf config.URLServiceConfig) *URLService {
+// The code pool is optional, generated codes are used directly if it is nil
+// The link previewer is optional too, no previews are fetched if it is nil
+// Without a geo locator, redirect rules matching on country never match
+func NewURLService(db *sql.DB, cacher Cacher, codeGenerator CodeGenerator, codeFilter CodeFilter, codePool CodePool, urlNormalizer URLNormalizer, urlScreener URLScreener, linkPreviewer LinkPreviewer, geoLocator GeoLocator, conf config.URLServiceConfig, warmupConf config.CacheWarmupConfig) *URLService {
+	clickFlushInterval := conf.VariantClickFlushInterval
+	if clickFlushInterval <= 0 {
+		clickFlushInterval = variantClickDefaultFlushInterval
+	}
+
 	return &URLService{
-		querier:           repo.New(db),
-		cacher:            cacher,
-		codeGenerator:     codeGenerator,
-		defaultExpiration: conf.DefaultExpiration,
-		ShortLinkBaseURL:  conf.ShortLinkBaseURL,
+		db:                 db,
+		querier:            repo.New(db),
+		cacher:             cacher,
+		codeGenerator:      codeGenerator,
+		codeFilter:         codeFilter,
+		codePool:           codePool,
+		urlNormalizer:      urlNormalizer,
+		urlScreener:        urlScreener,
+		linkPreviewer:      linkPreviewer,
+		geoLocator:         geoLocator,
+		defaultExpiration:  conf.DefaultExpiration,
+		clickFlushInterval: clickFlushInterval,
+		ShortLinkBaseURL:   conf.ShortLinkBaseURL,
+		warmupConf:         warmupConf,
 	}
 }
 
 // CreateShortURL creates a new shortened URL based on the provided request
 // And returns the response containing the shortened URL and its expiration date
 func (s *URLService) CreateShortURL(ctx context.Context, req model.CreateShortURLRequest) (*model.CreateShortURLResponse, error) {
+	// Retried requests with the same idempotency key get the result of the first one
+	if req.IdempotencyKey != "" {
+		return s.createShortURLIdempotent(ctx, req)
+	}
+	return s.createShortURL(ctx, req)
+}
+
+func (s *URLService) createShortURL(ctx context.Context, req model.CreateShortURLRequest) (*model.CreateShortURLResponse, error) {
+	// Normalize the URL, so that different spellings of the same target are recognized
+	canonicalURL, err := s.urlNormalizer.Normalize(req.OriginalURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize url: %w", err)
+	}
+
+	// Fill in what the request left out from the preferences of the user
+	if err := s.applyUserDefaults(ctx, &req); err != nil {
+		return nil, err
+	}
+
+	// Return the user's existing short URL of the same target if requested
+	if req.CustomCode == "" {
+		existingURL, err := s.findReusableURL(ctx, req, canonicalURL)
+		if err != nil {
+			return nil, err
+		}
+		if existingURL != nil {
+			return &model.CreateShortURLResponse{
+				ShortURL:         s.ShortLinkBaseURL + "/" + existingURL.ShortCode,
+				ExpiredAt:        existingURL.ExpiredAt.Time,
+				Reused:           true,
+				Quarantined:      existingURL.QuarantinedAt.Valid,
+				QuarantineReason: existingURL.QuarantineReason,
+			}, nil
+		}
+	}
+
+	// Screen the destinations, flagged URLs are created but quarantined
+	var variantDestinations []string
+	for _, variant := range req.Variants {
+		variantDestinations = append(variantDestinations, variant.Destination)
+	}
+	destinations := linkDestinations(req.OriginalURL, variantDestinations, req.RedirectRules, req.IOSDeepLink, req.AndroidDeepLink)
+	quarantineReason, err := screenDestinations(ctx, s.urlScreener, destinations)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rules are stored with the URL and evaluated on every redirect
+	redirectRules, err := encodeRedirectRules(req.RedirectRules)
+	if err != nil {
+		return nil, err
+	}
+
 	var shortCode string
 	var isCustom bool
 	// Check if a custom code is provided
 	if req.CustomCode != "" {
+		// Reject reserved paths and offensive words
+		if err := s.codeFilter.Check(req.CustomCode); err != nil {
+			return nil, fmt.Errorf("custom code %s cannot be used: %w", req.CustomCode, err)
+		}
 		// Check if the custom code is available
 		isAvailable, err := s.querier.IsShortCodeAvailable(ctx, req.CustomCode)
 		if err != nil {
@@ -54,8 +166,8 @@ func (s *URLService) CreateShortURL(ctx context.Context, req model.CreateShortUR
 		shortCode = req.CustomCode
 		isCustom = true
 	} else {
-		// Generate a new short code
-		generatedShortCode, err := s.tryGenerateShortCode(ctx, 10) // Try up to 10 times
+		// Take a short code from the pool or generate a new one
+		generatedShortCode, err := s.nextShortCode(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -79,8 +191,28 @@ func (s *URLService) CreateShortURL(ctx context.Context, req model.CreateShortUR
 		}
 	}
 
+	// Redirect with a 302 unless the owner asked otherwise
+	redirectType := req.RedirectType
+	if redirectType == "" {
+		redirectType = model.DefaultRedirectType
+	}
+
+	// The query string of the short URL is dropped unless the owner asked otherwise
+	queryPassthrough := req.QueryPassthrough
+	if queryPassthrough == "" {
+		queryPassthrough = model.QueryPassthroughNone
+	}
+
+	// Insert the URL and its variants in one transaction, so that a failing variant does not leave the URL live without it
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	querier := repo.New(tx)
+
 	// Insert into the database
-	urlInfo, err := s.querier.CreateURL(ctx, repo.CreateURLParams{
+	urlInfo, err := querier.CreateURL(ctx, repo.CreateURLParams{
 		OriginalUrl: req.OriginalURL,
 		ShortCode:   shortCode,
 		IsCustom:    isCustom,
@@ -92,49 +224,290 @@ func (s *URLService) CreateShortURL(ctx context.Context, req model.CreateShortUR
 			String: req.CreatedBy,
 			Valid:  req.CreatedBy != "",
 		},
+		CanonicalUrl: canonicalURL,
+		QuarantinedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: quarantineReason != "",
+		},
+		QuarantineReason: quarantineReason,
+		Title:            req.Title,
+		RedirectType:     redirectType,
+		UtmSource:        req.UTMSource,
+		UtmMedium:        req.UTMMedium,
+		UtmCampaign:      req.UTMCampaign,
+		UtmTerm:          req.UTMTerm,
+		UtmContent:       req.UTMContent,
+		QueryPassthrough: queryPassthrough,
+		RedirectRules:    redirectRules,
+		IosDeepLink:      req.IOSDeepLink,
+		AndroidDeepLink:  req.AndroidDeepLink,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// Custom codes and codes generated while the pool was empty may be pooled too, the pool must never hand them out
+	if s.codePool != nil {
+		if err := querier.RemoveShortCodeFromPool(ctx, shortCode); err != nil {
+			return nil, err
+		}
+	}
+
+	// Split the traffic across the variants if given
+	variants, err := createURLVariants(ctx, querier, urlInfo.ID, req.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	// Insert the URL info to the redis cache
-	if err := s.cacher.StoreURLToCache(ctx, urlInfo); err != nil {
+	if err := s.cacher.StoreURLToCache(ctx, model.CachedURL{Url: urlInfo, Variants: variants}); err != nil {
 		return nil, err
 	}
 
+	// Fetch the preview in the background, quarantined destinations are not visited
+	if s.linkPreviewer != nil && !urlInfo.QuarantinedAt.Valid {
+		s.linkPreviewer.Enqueue(urlInfo.ID, urlInfo.OriginalUrl)
+	}
+
 	return &model.CreateShortURLResponse{
-		ShortURL:  s.ShortLinkBaseURL + "/" + urlInfo.ShortCode,
-		ExpiredAt: urlInfo.ExpiredAt.Time,
+		ShortURL:         s.ShortLinkBaseURL + "/" + urlInfo.ShortCode,
+		ExpiredAt:        urlInfo.ExpiredAt.Time,
+		Quarantined:      urlInfo.QuarantinedAt.Valid,
+		QuarantineReason: urlInfo.QuarantineReason,
 	}, nil
 }
 
+// applyUserDefaults sets the duration, the redirect type and reusing of the request to the user's preferences if not provided
+// What the user did not set either is left to the server defaults
+func (s *URLService) applyUserDefaults(ctx context.Context, req *model.CreateShortURLRequest) error {
+	// Nothing to look up if the request sets everything
+	if req.Duration != nil && req.RedirectType != "" && req.ReuseExisting != nil {
+		return nil
+	}
+
+	userInfo, err := s.querier.GetUserInfoFromUsername(ctx, req.CreatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if req.Duration == nil && userInfo.DefaultDuration.Valid {
+		duration := int(userInfo.DefaultDuration.Int32)
+		req.Duration = &duration
+	}
+	if req.RedirectType == "" {
+		req.RedirectType = userInfo.DefaultRedirectType
+	}
+	if req.ReuseExisting == nil {
+		req.ReuseExisting = &userInfo.ReuseExistingUrls
+	}
+	return nil
+}
+
+// findReusableURL finds the user's active short URL with the same canonical URL if reusing is enabled
+// Returns nil if reusing is disabled or there is no such URL
+func (s *URLService) findReusableURL(ctx context.Context, req model.CreateShortURLRequest, canonicalURL string) (*repo.Url, error) {
+	if req.ReuseExisting == nil || !*req.ReuseExisting {
+		return nil, nil
+	}
+
+	// CreatedBy is the username of the JWT, so only the user's own URLs are reused
+	existingURL, err := s.querier.GetUserActiveURLByCanonicalURL(ctx, repo.GetUserActiveURLByCanonicalURLParams{
+		CreatedBy: sql.NullString{
+			String: req.CreatedBy,
+			Valid:  req.CreatedBy != "",
+		},
+		CanonicalUrl: canonicalURL,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &existingURL, nil
+}
+
 // GetLongURLInfo retrieves the original URL information based on the provided short URL
-func (s *URLService) GetLongURLInfo(ctx context.Context, shortCode string) (string, error) {
+// The destination is the first redirect rule matching the visitor, or the original URL if none match
+func (s *URLService) GetLongURLInfo(ctx context.Context, shortCode string, visitor model.Visitor) (*model.LongURLInfo, error) {
+	urlInfo, err := s.getURLInfo(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick the destination for the visitor
+	destination := urlInfo.OriginalUrl
+	var rules []model.RedirectRule
+	if len(urlInfo.RedirectRules) > 0 {
+		if err := json.Unmarshal(urlInfo.RedirectRules, &rules); err != nil {
+			// Broken rules must not break redirects either
+			log.Printf("failed to decode redirect rules of url %s: %v", urlInfo.ShortCode, err)
+		}
+	}
+	traits := &visitorTraits{visitor: visitor, geoLocator: s.geoLocator}
+	var variantID int64
+	if ruleDestination := traits.matchRedirectRules(rules, time.Now()); ruleDestination != "" {
+		destination = ruleDestination
+	} else if len(urlInfo.Variants) > 0 {
+		// Split the traffic of visitors not matching any rule
+		variant := pickVariant(urlInfo.Variants, visitor.VariantID)
+		destination = variant.Destination
+		variantID = variant.ID
+	}
+
+	// Try to open the app first on mobile devices, the destination is the fallback
+	deepLink := traits.deepLink(&urlInfo.Url)
+
+	// Destinations may turn malicious after the link was created, the screener caches its verdicts
+	// The background rescreen catches the destinations no one visits
+	if !urlInfo.QuarantinedAt.Valid {
+		if err := s.screenOnRedirect(ctx, urlInfo, destination, deepLink); err != nil {
+			// Screening failures must not break redirects
+			log.Printf("failed to screen url %s: %v", urlInfo.ShortCode, err)
+		}
+	}
+
+	// Visitors of quarantined links only get the warning page, which is neither a click nor a hit
+	// They are not assigned a variant either, so they get a fresh one once the link is released
+	if urlInfo.QuarantinedAt.Valid {
+		variantID = 0
+	} else {
+		if variantID != 0 {
+			s.countVariantClick(ctx, variantID)
+		}
+		s.countHit(ctx, shortCode)
+	}
+
+	// Finally, return the destination
+	return &model.LongURLInfo{
+		OriginalURL:      destination,
+		PerVisitor:       len(rules) > 0 || len(urlInfo.Variants) > 0 || urlInfo.IosDeepLink != "" || urlInfo.AndroidDeepLink != "",
+		VariantID:        variantID,
+		DeepLink:         deepLink,
+		RedirectType:     urlInfo.RedirectType,
+		ExpiredAt:        urlInfo.ExpiredAt.Time,
+		UTMParams:        utmParams(&urlInfo.Url),
+		QueryPassthrough: urlInfo.QueryPassthrough,
+		Quarantined:      urlInfo.QuarantinedAt.Valid,
+		QuarantineReason: urlInfo.QuarantineReason,
+	}, nil
+}
+
+// utmParams collects the UTM parameters set on the URL
+func utmParams(urlInfo *repo.Url) url.Values {
+	params := url.Values{}
+	for name, value := range map[string]string{
+		"utm_source":   urlInfo.UtmSource,
+		"utm_medium":   urlInfo.UtmMedium,
+		"utm_campaign": urlInfo.UtmCampaign,
+		"utm_term":     urlInfo.UtmTerm,
+		"utm_content":  urlInfo.UtmContent,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	return params
+}
+
+// GetURLPreview retrieves what the short URL points to without following it
+// Previews are not counted as hits
+func (s *URLService) GetURLPreview(ctx context.Context, shortCode string) (*model.URLPreviewInfo, error) {
+	urlInfo, err := s.getURLInfo(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &model.URLPreviewInfo{
+		ShortURL:         s.ShortLinkBaseURL + "/" + urlInfo.ShortCode,
+		OriginalURL:      urlInfo.OriginalUrl,
+		Title:            urlInfo.Title,
+		CreatedAt:        urlInfo.CreatedAt,
+		Quarantined:      urlInfo.QuarantinedAt.Valid,
+		QuarantineReason: urlInfo.QuarantineReason,
+	}
+	if urlInfo.ExpiredAt.Valid {
+		preview.ExpiredAt = &urlInfo.ExpiredAt.Time
+	}
+
+	return preview, nil
+}
+
+// getURLInfo gets the URL and its variants from the cache, falling back to the database
+func (s *URLService) getURLInfo(ctx context.Context, shortCode string) (*model.CachedURL, error) {
 	// Query the cache first to find if the short URL exists
-	originalURLFromCache, err := s.cacher.GetURLFromCache(ctx, shortCode)
+	urlInfoFromCache, err := s.cacher.GetURLFromCache(ctx, shortCode)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// If the URL exists in the cache, return the original URL
-	if originalURLFromCache != nil {
-		return originalURLFromCache.OriginalUrl, nil
+	// If the URL exists in the cache, return it
+	if urlInfoFromCache != nil {
+		return urlInfoFromCache, nil
 	}
 
 	// Otherwise, query the database
-	originalURLFromDB, err := s.querier.GetURLByShortCode(ctx, shortCode)
+	urlInfoFromDB, err := s.querier.GetURLByShortCode(ctx, shortCode)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	variants, err := s.querier.GetURLVariants(ctx, urlInfoFromDB.ID)
+	if err != nil {
+		return nil, err
 	}
+	cachedURL := model.CachedURL{Url: urlInfoFromDB, Variants: variants}
 
 	// Then store the URL info in the cache for future requests
-	err = s.cacher.StoreURLToCache(ctx, originalURLFromDB)
+	err = s.cacher.StoreURLToCache(ctx, cachedURL)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return &cachedURL, nil
+}
+
+// screenOnRedirect quarantines the URL in place if a destination the visitor is sent to is flagged
+func (s *URLService) screenOnRedirect(ctx context.Context, urlInfo *model.CachedURL, destination string, deepLink string) error {
+	destinations := []string{destination}
+	if deepLink != "" {
+		destinations = append(destinations, deepLink)
+	}
+	reason, err := screenDestinations(ctx, s.urlScreener, destinations)
+	if err != nil || reason == "" {
+		return err
 	}
 
-	// Finally, return the original URL
-	return originalURLFromDB.OriginalUrl, nil
+	urlInfo.QuarantinedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	urlInfo.QuarantineReason = reason
+
+	// Persist the quarantine and refresh the cached copy
+	if err := s.querier.QuarantineURL(ctx, repo.QuarantineURLParams{
+		ID:               urlInfo.ID,
+		QuarantineReason: reason,
+	}); err != nil {
+		return err
+	}
+	return s.cacher.StoreURLToCache(ctx, *urlInfo)
+}
+
+// countHit counts a redirect of the short code for the hot key ranking used by the cache warm-up
+// Nothing reads the ranking unless the warm-up preloads hot URLs, so it is not kept otherwise
+func (s *URLService) countHit(ctx context.Context, shortCode string) {
+	if !s.warmupConf.Enabled || s.warmupConf.HotLimit <= 0 {
+		return
+	}
+	if err := s.cacher.IncrURLHits(ctx, shortCode); err != nil {
+		log.Printf("failed to count hit for short code %s: %v", shortCode, err)
+	}
 }
 
 // Delete outdated URLs from the database
@@ -142,6 +515,47 @@ func (s *URLService) DeleteOutdatedURLs(ctx context.Context) error {
 	return s.querier.DeleteOutdatedURLs(ctx)
 }
 
+// ErrURLNotExtendable is returned when the short URL expired, was deleted, or its expiry changed since it was looked up
+var ErrURLNotExtendable = errors.New("short url cannot be extended")
+
+// ExtendShortURL pushes the expiry of the short URL back, if it still expires at the given time
+// The expiry is extended by the given duration, or the default expiration if 0
+// If the default expiration is 0 too, the short URL no longer expires
+func (s *URLService) ExtendShortURL(ctx context.Context, urlID int64, expiredAt time.Time, extendBy time.Duration) (*model.ExtendShortURLResponse, error) {
+	if extendBy == 0 {
+		extendBy = s.defaultExpiration
+	}
+	newExpiredAt := sql.NullTime{
+		Time:  expiredAt.Add(extendBy),
+		Valid: extendBy != 0,
+	}
+
+	urlInfo, err := s.querier.ExtendURLExpiration(ctx, repo.ExtendURLExpirationParams{
+		NewExpiredAt: newExpiredAt,
+		ID:           urlID,
+		ExpiredAt:    sql.NullTime{Time: expiredAt, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrURLNotExtendable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The cached copy still has the old expiry, it is cached again on the next redirect
+	if err := s.cacher.DeleteURLFromCache(ctx, urlInfo.ShortCode); err != nil {
+		return nil, err
+	}
+
+	resp := &model.ExtendShortURLResponse{
+		ShortURL: s.ShortLinkBaseURL + "/" + urlInfo.ShortCode,
+	}
+	if urlInfo.ExpiredAt.Valid {
+		resp.ExpiredAt = &urlInfo.ExpiredAt.Time
+	}
+	return resp, nil
+}
+
 // Get URLs created by the user
 func (s *URLService) GetMyURLs(ctx context.Context, req model.GetUserShortURLsRequest, username string) (*model.GetUserShortURLsResponse, error) {
 	// Retrieve the URLs created by the user from the database
@@ -157,8 +571,54 @@ func (s *URLService) GetMyURLs(ctx context.Context, req model.GetUserShortURLsRe
 		return nil, err
 	}
 
+	// The health checks, variants and previews are looked up for the URLs of the page
+	urlIDs := make([]int64, 0, len(urls))
+	for _, urlInfo := range urls {
+		urlIDs = append(urlIDs, urlInfo.ID)
+	}
+
+	healthChecks, err := s.querier.GetURLHealthChecksByURLIDs(ctx, urlIDs)
+	if err != nil {
+		return nil, err
+	}
+	healthByURLID := make(map[int64]repo.UrlHealthCheck, len(healthChecks))
+	for _, healthCheck := range healthChecks {
+		healthByURLID[healthCheck.UrlID] = healthCheck
+	}
+
+	variants, err := s.querier.GetURLVariantsByURLIDs(ctx, urlIDs)
+	if err != nil {
+		return nil, err
+	}
+	variantsByURLID := make(map[int64][]repo.UrlVariant)
+	for _, variant := range variants {
+		variantsByURLID[variant.UrlID] = append(variantsByURLID[variant.UrlID], variant)
+	}
+
+	previews, err := s.querier.GetURLPreviewsByURLIDs(ctx, urlIDs)
+	if err != nil {
+		return nil, err
+	}
+	previewByURLID := make(map[int64]repo.UrlPreview, len(previews))
+	for _, preview := range previews {
+		previewByURLID[preview.UrlID] = preview
+	}
+
+	userURLs := make([]model.UserShortURL, 0, len(urls))
+	for _, urlInfo := range urls {
+		userURL := model.UserShortURL{Url: urlInfo, Variants: variantsByURLID[urlInfo.ID]}
+		if healthCheck, ok := healthByURLID[urlInfo.ID]; ok {
+			userURL.IsBroken = healthCheck.IsBroken
+			userURL.Health = &healthCheck
+		}
+		if preview, ok := previewByURLID[urlInfo.ID]; ok {
+			userURL.Preview = &preview
+		}
+		userURLs = append(userURLs, userURL)
+	}
+
 	return &model.GetUserShortURLsResponse{
-		URLs: urls,
+		URLs: userURLs,
 	}, nil
 }
 
@@ -168,6 +628,10 @@ func (s *URLService) DeleteShortURL(ctx context.Context, req model.DeleteUserSho
 	if err := s.cacher.DeleteURLFromCache(ctx, req.ShortCode); err != nil {
 		return nil, err
 	}
+	// The deleted URL should not be preloaded by the next warm-up
+	if err := s.cacher.RemoveHotShortCodes(ctx, req.ShortCode); err != nil {
+		log.Printf("failed to remove short code %s from hot keys: %v", req.ShortCode, err)
+	}
 
 	// Delete the URL from the database
 	err := s.querier.DeleteURLFromId(ctx, repo.DeleteURLFromIdParams{
@@ -186,6 +650,24 @@ func (s *URLService) DeleteShortURL(ctx context.Context, req model.DeleteUserSho
 	}, nil
 }
 
+// nextShortCode takes an unused short code from the pool, falling back to generating one
+func (s *URLService) nextShortCode(ctx context.Context) (string, error) {
+	if s.codePool != nil {
+		shortCode, err := s.codePool.TakeShortCode(ctx)
+		if err != nil {
+			return "", err
+		}
+		// Codes from the pool are known to be unused, no need to check them again
+		if shortCode != "" {
+			return shortCode, nil
+		}
+		log.Println("Short code pool is empty, generating a short code on the create path")
+	}
+
+	// Generate a new short code
+	return s.tryGenerateShortCode(ctx, 10) // Try up to 10 times
+}
+
 // Generate the short code, search for availability
 // If available, insert into the database
 // Otherwise, generate a new code and repeat
@@ -195,7 +677,16 @@ func (s *URLService) tryGenerateShortCode(ctx context.Context, maxTryTimes int)
 	}
 
 	// Generate a short code using the provided generator
-	shortCode := s.codeGenerator.GenerateShortCode()
+	shortCode, err := s.codeGenerator.GenerateShortCode(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Blocked codes are discarded like taken ones
+	if err := s.codeFilter.Check(shortCode); err != nil {
+		return s.tryGenerateShortCode(ctx, maxTryTimes-1)
+	}
+
 	// Check if the generated short code is available
 	isAvailable, err := s.querier.IsShortCodeAvailable(ctx, shortCode)
 	if err != nil {
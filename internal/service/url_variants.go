@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+)
+
+// How often the clicks of variants are added to the database if no interval is configured
+const variantClickDefaultFlushInterval = time.Minute
+
+// createURLVariants stores the destinations the traffic of the new URL is split across
+// The querier is the transaction creating the URL, so that a URL is never live without all of its variants
+func createURLVariants(ctx context.Context, querier repo.Querier, urlID int64, variants []model.URLVariant) ([]repo.UrlVariant, error) {
+	created := make([]repo.UrlVariant, 0, len(variants))
+	for _, variant := range variants {
+		urlVariant, err := querier.CreateURLVariant(ctx, repo.CreateURLVariantParams{
+			UrlID:       urlID,
+			Destination: variant.Destination,
+			Weight:      variant.Weight,
+		})
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, urlVariant)
+	}
+	return created, nil
+}
+
+// pickVariant returns the variant the visitor was sent to before if it still exists
+// Otherwise a variant is drawn at random according to the weights
+func pickVariant(variants []repo.UrlVariant, stickyID int64) repo.UrlVariant {
+	var totalWeight int64
+	for _, variant := range variants {
+		if variant.ID == stickyID {
+			return variant
+		}
+		totalWeight += int64(variant.Weight)
+	}
+
+	n := rand.Int64N(totalWeight)
+	for _, variant := range variants {
+		n -= int64(variant.Weight)
+		if n < 0 {
+			return variant
+		}
+	}
+	return variants[len(variants)-1]
+}
+
+// countVariantClick attributes a redirect to the variant
+// Clicks are counted in redis and added to the database by FlushVariantClicks, so redirects do not contend on the row
+func (s *URLService) countVariantClick(ctx context.Context, variantID int64) {
+	if err := s.cacher.IncrVariantClicks(ctx, variantID, 1); err != nil {
+		log.Printf("failed to count click for variant %d: %v", variantID, err)
+	}
+}
+
+// VariantClickFlushInterval returns how often FlushVariantClicks should run
+func (s *URLService) VariantClickFlushInterval() time.Duration {
+	return s.clickFlushInterval
+}
+
+// FlushVariantClicks adds the clicks counted in redis to the variants in the database
+func (s *URLService) FlushVariantClicks(ctx context.Context) error {
+	clicks, err := s.cacher.TakeVariantClicks(ctx)
+	if err != nil {
+		return err
+	}
+
+	var flushErr error
+	for variantID, count := range clicks {
+		err := s.querier.AddURLVariantClicks(ctx, repo.AddURLVariantClicksParams{
+			ID:     variantID,
+			Clicks: count,
+		})
+		if err == nil {
+			continue
+		}
+		flushErr = err
+		// Put the clicks back, so that they are added on the next flush
+		if err := s.cacher.IncrVariantClicks(ctx, variantID, count); err != nil {
+			log.Printf("lost %d clicks of variant %d: %v", count, variantID, err)
+		}
+	}
+	return flushErr
+}
+
+// withVariants pairs the URLs with their variants for the cache
+func withVariants(urlInfos []repo.Url, variants []repo.UrlVariant) []model.CachedURL {
+	variantsByURLID := make(map[int64][]repo.UrlVariant)
+	for _, variant := range variants {
+		variantsByURLID[variant.UrlID] = append(variantsByURLID[variant.UrlID], variant)
+	}
+
+	cachedURLs := make([]model.CachedURL, 0, len(urlInfos))
+	for _, urlInfo := range urlInfos {
+		cachedURLs = append(cachedURLs, model.CachedURL{
+			Url:      urlInfo,
+			Variants: variantsByURLID[urlInfo.ID],
+		})
+	}
+	return cachedURLs
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+)
+
+// WarmUpCache preloads the most recently created and the most clicked active URLs into the cache
+func (s *URLService) WarmUpCache(ctx context.Context) error {
+	// Collect the URLs to preload, keyed by short code to avoid storing duplicates
+	urlInfos := make([]model.CachedURL, 0, s.warmupConf.RecentLimit+s.warmupConf.HotLimit)
+	seen := make(map[string]struct{})
+
+	// Most clicked URLs come first, as they are the ones most likely to be requested
+	hotURLs, err := s.getHotURLs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, urlInfo := range hotURLs {
+		seen[urlInfo.ShortCode] = struct{}{}
+		urlInfos = append(urlInfos, urlInfo)
+	}
+
+	// Then the most recently created URLs
+	if s.warmupConf.RecentLimit > 0 {
+		recentURLs, err := s.querier.GetRecentActiveURLs(ctx, int32(s.warmupConf.RecentLimit))
+		if err != nil {
+			return err
+		}
+		recentVariants, err := s.querier.GetRecentActiveURLVariants(ctx, int32(s.warmupConf.RecentLimit))
+		if err != nil {
+			return err
+		}
+		for _, urlInfo := range withVariants(recentURLs, recentVariants) {
+			if _, ok := seen[urlInfo.ShortCode]; ok {
+				continue
+			}
+			seen[urlInfo.ShortCode] = struct{}{}
+			urlInfos = append(urlInfos, urlInfo)
+		}
+	}
+
+	// Store the URLs to the cache in pipelined batches
+	batchSize := max(s.warmupConf.BatchSize, 1)
+	for start := 0; start < len(urlInfos); start += batchSize {
+		end := min(start+batchSize, len(urlInfos))
+		if err := s.cacher.StoreURLsToCache(ctx, urlInfos[start:end]); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Cache warm-up finished, preloaded %d URLs", len(urlInfos))
+	return nil
+}
+
+// getHotURLs looks up the most clicked short codes that are still active in the database
+func (s *URLService) getHotURLs(ctx context.Context) ([]model.CachedURL, error) {
+	if s.warmupConf.HotLimit <= 0 {
+		return nil, nil
+	}
+
+	// Keep the click ranking from growing without bound
+	if s.warmupConf.HotKeysTracked > 0 {
+		if err := s.cacher.TrimHotShortCodes(ctx, int64(s.warmupConf.HotKeysTracked)); err != nil {
+			return nil, err
+		}
+	}
+
+	// The ranking is empty after a redis flush, in which case only recent URLs are preloaded
+	shortCodes, err := s.cacher.GetHotShortCodes(ctx, int64(s.warmupConf.HotLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	urlInfos := make([]model.CachedURL, 0, len(shortCodes))
+	var staleShortCodes []string
+	for _, shortCode := range shortCodes {
+		urlInfo, err := s.querier.GetURLByShortCode(ctx, shortCode)
+		// Expired or deleted URLs are dropped from the ranking
+		if errors.Is(err, sql.ErrNoRows) {
+			staleShortCodes = append(staleShortCodes, shortCode)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		variants, err := s.querier.GetURLVariants(ctx, urlInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+		urlInfos = append(urlInfos, model.CachedURL{Url: urlInfo, Variants: variants})
+	}
+
+	if err := s.cacher.RemoveHotShortCodes(ctx, staleShortCodes...); err != nil {
+		log.Printf("failed to remove stale hot keys: %v", err)
+	}
+
+	return urlInfos, nil
+}
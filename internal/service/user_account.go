@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/mailer"
+)
+
+var (
+	ErrEmailUnchanged    = errors.New("the new email is the current email of the account")
+	ErrEmailTaken        = errors.New("email already exists")
+	ErrIncorrectPassword = errors.New("your password is incorrect, please try again")
+	// Tokens keep working until they expire, even if the account was deleted
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// RequestEmailChange sends codes to the current and the new email of the user, both are needed to change it
+func (s *UserService) RequestEmailChange(ctx context.Context, req model.RequestEmailChangeRequest) error {
+	userInfo, err := s.getUser(ctx, req.UserID)
+	if err != nil {
+		return err
+	}
+	if req.NewEmail == userInfo.Email {
+		return ErrEmailUnchanged
+	}
+	if err := s.checkEmailAvailable(ctx, req.NewEmail); err != nil {
+		return err
+	}
+
+	// Both addresses get an email, so both are subject to the cooldown
+	for _, email := range []string{userInfo.Email, req.NewEmail} {
+		if err := s.checkEmailCodeCooldown(ctx, email); err != nil {
+			return err
+		}
+	}
+
+	currentCode, err := s.issueEmailCode(ctx, model.EmailCodeChangeEmailCurrent, userInfo.Email)
+	if err != nil {
+		return err
+	}
+	newCode, err := s.issueEmailCode(ctx, model.EmailCodeChangeEmailNew, req.NewEmail)
+	if err != nil {
+		return err
+	}
+	expiresAt := s.emailCodeExpiresAt()
+
+	err = s.mailer.SendTemplate(ctx, userInfo.Email, mailer.TemplateEmailCodeChangeEmailCurrent, userInfo.Language, mailer.EmailChangeCodeData{
+		Username:  userInfo.Username,
+		Code:      currentCode,
+		NewEmail:  req.NewEmail,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	err = s.mailer.SendTemplate(ctx, req.NewEmail, mailer.TemplateEmailCodeChangeEmailNew, userInfo.Language, mailer.EmailChangeCodeData{
+		Username:  userInfo.Username,
+		Code:      newCode,
+		NewEmail:  req.NewEmail,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The cooldowns only start once both emails are queued
+	for _, email := range []string{userInfo.Email, req.NewEmail} {
+		if err := s.cacher.StartEmailCodeCooldown(ctx, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChangeEmail changes the email of the user once the codes sent to both addresses are confirmed
+func (s *UserService) ChangeEmail(ctx context.Context, req model.ChangeEmailRequest) error {
+	userInfo, err := s.getUser(ctx, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	// The code of the new address can only match if it was requested for the same address
+	if err := s.verifyEmailCode(ctx, model.EmailCodeChangeEmailNew, req.NewEmailCode, req.NewEmail, req.ClientIP); err != nil {
+		return err
+	}
+	if err := s.verifyEmailCode(ctx, model.EmailCodeChangeEmailCurrent, req.CurrentEmailCode, userInfo.Email, req.ClientIP); err != nil {
+		return err
+	}
+
+	// The address may have been taken since the codes were sent
+	if err := s.checkEmailAvailable(ctx, req.NewEmail); err != nil {
+		return err
+	}
+
+	return s.querier.UpdateUserEmail(ctx, repo.UpdateUserEmailParams{
+		UserID: req.UserID,
+		Email:  req.NewEmail,
+	})
+}
+
+// DeleteAccount deletes the user after checking the password again
+// The short URLs are deleted with the user if requested, otherwise they are kept without an owner
+func (s *UserService) DeleteAccount(ctx context.Context, req model.DeleteAccountRequest) error {
+	userInfo, err := s.getUser(ctx, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Wrong passwords count as failed logins, so that this cannot be used to guess them
+	loginReq := model.LoginRequest{Username: userInfo.Username, ClientIP: req.ClientIP}
+	if err := s.checkLockout(ctx, loginAccountKey(loginReq.Username), loginIPKey(loginReq.ClientIP)); err != nil {
+		return err
+	}
+	if err := s.pwdManager.ValidatePassword(userInfo.PasswordHash, req.Password); err != nil {
+		if err := s.loginFailed(ctx, loginReq, &userInfo); err != nil {
+			return err
+		}
+		return ErrIncorrectPassword
+	}
+
+	createdBy := sql.NullString{String: userInfo.Username, Valid: true}
+	shortCodes, err := s.querier.GetUserShortCodes(ctx, createdBy)
+	if err != nil {
+		return err
+	}
+
+	// Delete the links and the user in one transaction, so that neither is deleted without the other
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	querier := repo.New(tx)
+
+	if req.DeleteURLs {
+		if err := querier.DeleteUserURLs(ctx, createdBy); err != nil {
+			return err
+		}
+	}
+	if err := querier.DeleteUser(ctx, userInfo.UserID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// The cached copies still have the owner, or the links are gone altogether
+	// The account is already deleted, so failing to purge them is not an error
+	for _, shortCode := range shortCodes {
+		if err := s.cacher.DeleteURLFromCache(ctx, shortCode); err != nil {
+			log.Printf("failed to delete short code %s from cache: %v", shortCode, err)
+		}
+	}
+	if req.DeleteURLs && len(shortCodes) > 0 {
+		if err := s.cacher.RemoveHotShortCodes(ctx, shortCodes...); err != nil {
+			log.Printf("failed to remove short codes of %s from hot keys: %v", userInfo.Username, err)
+		}
+	}
+	// A new account with the same username should not inherit the failed logins
+	if err := s.cacher.ClearFailedAttempts(ctx, loginAccountKey(userInfo.Username)); err != nil {
+		log.Printf("failed to clear failed logins: %v", err)
+	}
+
+	return nil
+}
+
+// CheckTokenUser returns ErrUserNotFound unless the user the token was issued to still exists
+// A new account may take the username of a deleted one, so the user ID must match as well
+func (s *UserService) CheckTokenUser(ctx context.Context, userID string, username string) error {
+	userInfo, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if userInfo.Username != username {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// getUser gets the user of the token, ErrUserNotFound if the account was deleted
+func (s *UserService) getUser(ctx context.Context, userID string) (repo.User, error) {
+	userInfo, err := s.querier.GetUserInfoFromUserID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userInfo, ErrUserNotFound
+	}
+	return userInfo, err
+}
+
+// checkEmailAvailable returns ErrEmailTaken if an account uses the email
+func (s *UserService) checkEmailAvailable(ctx context.Context, email string) error {
+	_, err := s.querier.GetUserInfoFromEmail(ctx, email)
+	if err == nil {
+		return ErrEmailTaken
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
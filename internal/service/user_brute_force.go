@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/mailer"
+)
+
+var (
+	ErrInvalidEmailCode    = errors.New("invalid or expired email code")
+	ErrTooManyCodeAttempts = errors.New("too many wrong email codes, please request a new one")
+)
+
+// Defaults of the brute force settings that are not configured, a missing section must not turn the protection off
+const (
+	bruteForceDefaultMaxAccountFailures     = 5
+	bruteForceDefaultMaxIPFailures          = 20
+	bruteForceDefaultFailureWindow          = 15 * time.Minute
+	bruteForceDefaultBaseLockout            = time.Minute
+	bruteForceDefaultMaxLockout             = 24 * time.Hour
+	bruteForceDefaultLockoutMemory          = 24 * time.Hour
+	bruteForceDefaultMaxEmailCodeAttempts   = 5
+	bruteForceDefaultMaxEmailCodeIPFailures = 20
+)
+
+// bruteForceConfWithDefaults fills in the settings that are not configured
+// Limits are only turned off by a negative value
+func bruteForceConfWithDefaults(conf config.BruteForceConfig) config.BruteForceConfig {
+	if conf.MaxAccountFailures == 0 {
+		conf.MaxAccountFailures = bruteForceDefaultMaxAccountFailures
+	}
+	if conf.MaxIPFailures == 0 {
+		conf.MaxIPFailures = bruteForceDefaultMaxIPFailures
+	}
+	if conf.MaxEmailCodeAttempts == 0 {
+		conf.MaxEmailCodeAttempts = bruteForceDefaultMaxEmailCodeAttempts
+	}
+	if conf.MaxEmailCodeIPFailures == 0 {
+		conf.MaxEmailCodeIPFailures = bruteForceDefaultMaxEmailCodeIPFailures
+	}
+	// Without a window the counter expires right away and never reaches the limit
+	if conf.FailureWindow <= 0 {
+		conf.FailureWindow = bruteForceDefaultFailureWindow
+	}
+	// Lockouts without a duration would never lock anything
+	if conf.BaseLockout <= 0 {
+		conf.BaseLockout = bruteForceDefaultBaseLockout
+	}
+	if conf.MaxLockout <= 0 {
+		conf.MaxLockout = bruteForceDefaultMaxLockout
+	}
+	conf.MaxLockout = max(conf.MaxLockout, conf.BaseLockout)
+	if conf.LockoutMemory <= 0 {
+		conf.LockoutMemory = bruteForceDefaultLockoutMemory
+	}
+	return conf
+}
+
+// LockedOutError is returned while too many failed attempts lock out an account or an address
+type LockedOutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return fmt.Sprintf("too many failed attempts, please try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// EmailCodeCooldownError is returned when another email code is requested too soon
+type EmailCodeCooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *EmailCodeCooldownError) Error() string {
+	return fmt.Sprintf("an email code was sent recently, please try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Keys of the failure counters and lockouts, emails are case-insensitive
+func loginAccountKey(username string) string { return "login:user:" + username }
+func loginIPKey(ip string) string            { return "login:ip:" + ip }
+func emailCodeEmailKey(purpose, email string) string {
+	return "email_code:" + purpose + ":" + strings.ToLower(email)
+}
+func emailCodeIPKey(ip string) string { return "email_code:ip:" + ip }
+
+// checkLockout returns a LockedOutError if any of the keys is locked
+func (s *UserService) checkLockout(ctx context.Context, keys ...string) error {
+	var retryAfter time.Duration
+	for _, key := range keys {
+		lockout, err := s.cacher.GetLockout(ctx, key)
+		if err != nil {
+			return err
+		}
+		retryAfter = max(retryAfter, lockout)
+	}
+
+	if retryAfter > 0 {
+		return &LockedOutError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt on the key, locking it out once the limit is reached
+// Each lockout within the lockout memory lasts twice as long as the previous one
+// Returns the duration of the lockout, or 0 if the key is not locked
+func (s *UserService) recordFailure(ctx context.Context, key string, limit int) (time.Duration, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+
+	failures, err := s.cacher.IncrFailedAttempts(ctx, key, s.bruteForceConf.FailureWindow)
+	if err != nil {
+		return 0, err
+	}
+	if failures < int64(limit) {
+		return 0, nil
+	}
+
+	lockouts, err := s.cacher.IncrFailedAttempts(ctx, "lockouts:"+key, s.bruteForceConf.LockoutMemory)
+	if err != nil {
+		return 0, err
+	}
+	lockout := s.lockoutDuration(lockouts)
+	if err := s.cacher.LockOut(ctx, key, lockout); err != nil {
+		return 0, err
+	}
+	// The count starts over once the lockout ends
+	if err := s.cacher.ClearFailedAttempts(ctx, key); err != nil {
+		return 0, err
+	}
+
+	return lockout, nil
+}
+
+// lockoutDuration returns how long the given lockout within the lockout memory lasts, the first one lasting the base duration
+func (s *UserService) lockoutDuration(lockouts int64) time.Duration {
+	lockout := s.bruteForceConf.BaseLockout
+	for range lockouts - 1 {
+		lockout *= 2
+		if lockout >= s.bruteForceConf.MaxLockout {
+			return s.bruteForceConf.MaxLockout
+		}
+	}
+	return lockout
+}
+
+// loginFailed records a failed login of the user from the address
+// Returns a LockedOutError if the account or the address got locked
+func (s *UserService) loginFailed(ctx context.Context, req model.LoginRequest, userInfo *repo.User) error {
+	accountLockout, err := s.recordFailure(ctx, loginAccountKey(req.Username), s.bruteForceConf.MaxAccountFailures)
+	if err != nil {
+		return err
+	}
+	ipLockout, err := s.recordFailure(ctx, loginIPKey(req.ClientIP), s.bruteForceConf.MaxIPFailures)
+	if err != nil {
+		return err
+	}
+
+	// Let the owner know someone is guessing their password
+	if accountLockout > 0 && userInfo != nil && s.bruteForceConf.NotifyOwner {
+		err := s.mailer.SendTemplate(ctx, userInfo.Email, mailer.TemplateAccountLocked, userInfo.Language, mailer.AccountLockedData{
+			Username:    userInfo.Username,
+			Failures:    s.bruteForceConf.MaxAccountFailures,
+			ClientIP:    req.ClientIP,
+			LockedUntil: time.Now().Add(accountLockout).In(userLocation(userInfo.Timezone)),
+		})
+		if err != nil {
+			log.Printf("failed to send the lockout email of %s: %v", userInfo.Username, err)
+		}
+	}
+
+	if lockout := max(accountLockout, ipLockout); lockout > 0 {
+		return &LockedOutError{RetryAfter: lockout}
+	}
+	return nil
+}
+
+// verifyEmailCode checks the code sent to the email for the purpose, and consumes it if it matches
+// The code is invalidated after too many wrong tries, and addresses sending too many wrong codes are locked
+func (s *UserService) verifyEmailCode(ctx context.Context, purpose string, emailCode string, email string, clientIP string) error {
+	if err := s.checkLockout(ctx, emailCodeIPKey(clientIP)); err != nil {
+		return err
+	}
+
+	// Get the active code of the email from cacher, nil if it is invalid or expired
+	activeCode, err := s.cacher.GetEmailCode(ctx, purpose, email)
+	if err != nil {
+		return err
+	}
+	if activeCode == nil || subtle.ConstantTimeCompare([]byte(*activeCode), []byte(emailCode)) != 1 {
+		return s.emailCodeFailed(ctx, purpose, email, clientIP)
+	}
+
+	// Code matches, delete it so that it is only used once
+	if err := s.cacher.DeleteEmailCode(ctx, purpose, email); err != nil {
+		log.Printf("failed to delete email code: %v", err)
+	}
+	if err := s.cacher.ClearFailedAttempts(ctx, emailCodeEmailKey(purpose, email)); err != nil {
+		log.Printf("failed to clear email code attempts: %v", err)
+	}
+
+	return nil
+}
+
+// emailCodeFailed records a wrong code for the email and the purpose from the address
+func (s *UserService) emailCodeFailed(ctx context.Context, purpose string, email string, clientIP string) error {
+	if lockout, err := s.recordFailure(ctx, emailCodeIPKey(clientIP), s.bruteForceConf.MaxEmailCodeIPFailures); err != nil {
+		return err
+	} else if lockout > 0 {
+		return &LockedOutError{RetryAfter: lockout}
+	}
+
+	if s.bruteForceConf.MaxEmailCodeAttempts <= 0 {
+		return ErrInvalidEmailCode
+	}
+	attempts, err := s.cacher.IncrFailedAttempts(ctx, emailCodeEmailKey(purpose, email), s.bruteForceConf.FailureWindow)
+	if err != nil {
+		return err
+	}
+	if attempts < int64(s.bruteForceConf.MaxEmailCodeAttempts) {
+		return ErrInvalidEmailCode
+	}
+
+	// Too many wrong tries, the code cannot be guessed anymore
+	if err := s.cacher.DeleteEmailCode(ctx, purpose, email); err != nil {
+		return err
+	}
+	return ErrTooManyCodeAttempts
+}
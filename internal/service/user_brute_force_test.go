@@ -0,0 +1,87 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	s := NewUserService(nil, nil, nil, nil, nil, 0, config.BruteForceConfig{
+		BaseLockout: time.Minute,
+		MaxLockout:  10 * time.Minute,
+	})
+
+	tests := []struct {
+		lockouts int64
+		want     time.Duration
+	}{
+		{lockouts: 1, want: time.Minute},
+		{lockouts: 2, want: 2 * time.Minute},
+		{lockouts: 3, want: 4 * time.Minute},
+		{lockouts: 4, want: 8 * time.Minute},
+		{lockouts: 5, want: 10 * time.Minute},
+		{lockouts: 1000, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := s.lockoutDuration(tt.lockouts); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %v, want %v", tt.lockouts, got, tt.want)
+		}
+	}
+}
+
+func TestBruteForceConfWithDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		conf config.BruteForceConfig
+		want config.BruteForceConfig
+	}{
+		{
+			name: "missing section",
+			conf: config.BruteForceConfig{},
+			want: config.BruteForceConfig{
+				MaxAccountFailures:     bruteForceDefaultMaxAccountFailures,
+				MaxIPFailures:          bruteForceDefaultMaxIPFailures,
+				FailureWindow:          bruteForceDefaultFailureWindow,
+				BaseLockout:            bruteForceDefaultBaseLockout,
+				MaxLockout:             bruteForceDefaultMaxLockout,
+				LockoutMemory:          bruteForceDefaultLockoutMemory,
+				MaxEmailCodeAttempts:   bruteForceDefaultMaxEmailCodeAttempts,
+				MaxEmailCodeIPFailures: bruteForceDefaultMaxEmailCodeIPFailures,
+			},
+		},
+		{
+			name: "limits turned off and maximum below the base",
+			conf: config.BruteForceConfig{
+				MaxAccountFailures:     -1,
+				MaxIPFailures:          -1,
+				FailureWindow:          time.Hour,
+				BaseLockout:            time.Hour,
+				MaxLockout:             time.Minute,
+				LockoutMemory:          time.Hour,
+				MaxEmailCodeAttempts:   -1,
+				MaxEmailCodeIPFailures: -1,
+			},
+			want: config.BruteForceConfig{
+				MaxAccountFailures:     -1,
+				MaxIPFailures:          -1,
+				FailureWindow:          time.Hour,
+				BaseLockout:            time.Hour,
+				MaxLockout:             time.Hour,
+				LockoutMemory:          time.Hour,
+				MaxEmailCodeAttempts:   -1,
+				MaxEmailCodeIPFailures: -1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bruteForceConfWithDefaults(tt.conf); got != tt.want {
+				t.Errorf("bruteForceConfWithDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
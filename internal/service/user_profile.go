@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ZureTz/shorter-url/internal/model"
+	"github.com/ZureTz/shorter-url/internal/repo"
+)
+
+// GetProfile gets the profile and the preferences of the user
+func (s *UserService) GetProfile(ctx context.Context, userID string) (*model.UserProfile, error) {
+	userInfo, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return newUserProfile(userInfo), nil
+}
+
+// UpdateProfile changes the fields of the profile given in the request and returns the updated profile
+func (s *UserService) UpdateProfile(ctx context.Context, req model.UpdateProfileRequest) (*model.UserProfile, error) {
+	userInfo, err := s.getUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := repo.UpdateUserProfileParams{
+		UserID:              userInfo.UserID,
+		DisplayName:         userInfo.DisplayName,
+		Language:            userInfo.Language,
+		Timezone:            userInfo.Timezone,
+		DefaultDuration:     userInfo.DefaultDuration,
+		DefaultRedirectType: userInfo.DefaultRedirectType,
+		ReuseExistingUrls:   userInfo.ReuseExistingUrls,
+		ExpiryReminders:     userInfo.ExpiryReminders,
+	}
+	if req.DisplayName != nil {
+		params.DisplayName = *req.DisplayName
+	}
+	if req.Language != nil {
+		params.Language = s.mailer.MatchLanguage(*req.Language)
+	}
+	if req.Timezone != nil {
+		params.Timezone = *req.Timezone
+	}
+	if req.DefaultDuration != nil {
+		// 0 goes back to the server default
+		params.DefaultDuration = sql.NullInt32{
+			Int32: int32(*req.DefaultDuration),
+			Valid: *req.DefaultDuration > 0,
+		}
+	}
+	if req.DefaultRedirectType != nil {
+		params.DefaultRedirectType = *req.DefaultRedirectType
+	}
+	if req.ReuseExistingURLs != nil {
+		params.ReuseExistingUrls = *req.ReuseExistingURLs
+	}
+	if req.ExpiryReminders != nil {
+		params.ExpiryReminders = *req.ExpiryReminders
+	}
+
+	updatedUser, err := s.querier.UpdateUserProfile(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newUserProfile(updatedUser), nil
+}
+
+func newUserProfile(userInfo repo.User) *model.UserProfile {
+	profile := &model.UserProfile{
+		UserID:              userInfo.UserID,
+		Username:            userInfo.Username,
+		Email:               userInfo.Email,
+		DisplayName:         userInfo.DisplayName,
+		Language:            userInfo.Language,
+		Timezone:            userInfo.Timezone,
+		DefaultRedirectType: userInfo.DefaultRedirectType,
+		ReuseExistingURLs:   userInfo.ReuseExistingUrls,
+		ExpiryReminders:     userInfo.ExpiryReminders,
+		CreatedAt:           userInfo.CreatedAt,
+	}
+	if userInfo.DefaultDuration.Valid {
+		defaultDuration := int(userInfo.DefaultDuration.Int32)
+		profile.DefaultDuration = &defaultDuration
+	}
+	return profile
+}
+
+// userLocation gets the time zone of a user, times are shown in UTC if it cannot be loaded
+func userLocation(timezone string) *time.Location {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
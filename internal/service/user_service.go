@@ -9,8 +9,10 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/ZureTz/shorter-url/config"
 	"github.com/ZureTz/shorter-url/internal/model"
 	"github.com/ZureTz/shorter-url/internal/repo"
+	"github.com/ZureTz/shorter-url/pkg/mailer"
 )
 
 type JWTGenerator interface {
@@ -25,41 +27,67 @@ type PasswordManager interface {
 }
 
 type Mailer interface {
-	SendEmail(to string, subject string, body string)
+	SendTemplate(ctx context.Context, to string, template string, language string, data any) error
+	MatchLanguage(preferred string) string
 }
 
 type UserService struct {
+	db           *sql.DB
 	querier      repo.Querier
 	cacher       Cacher
 	jwtGenerator JWTGenerator
 	pwdManager   PasswordManager
 	mailer       Mailer
+
+	// Emails with codes are not sent once the codes expired
+	emailCodeExpiration time.Duration
+	bruteForceConf      config.BruteForceConfig
 }
 
-func NewUserService(db *sql.DB, cacher Cacher, jwtGen JWTGenerator, pwdManager PasswordManager, mailer Mailer) *UserService {
+func NewUserService(db *sql.DB, cacher Cacher, jwtGen JWTGenerator, pwdManager PasswordManager, mailer Mailer, emailCodeExpiration time.Duration, bruteForceConf config.BruteForceConfig) *UserService {
 	return &UserService{
-		querier:      repo.New(db),
-		cacher:       cacher,
-		jwtGenerator: jwtGen,
-		pwdManager:   pwdManager,
-		mailer:       mailer,
+		db:                  db,
+		querier:             repo.New(db),
+		cacher:              cacher,
+		jwtGenerator:        jwtGen,
+		pwdManager:          pwdManager,
+		mailer:              mailer,
+		emailCodeExpiration: emailCodeExpiration,
+		bruteForceConf:      bruteForceConfWithDefaults(bruteForceConf),
 	}
 }
 
 // UserLogin handles user login requests
 func (s *UserService) UserLogin(ctx context.Context, req model.LoginRequest) (*model.LoginResponse, error) {
+	// Refuse to check passwords while the account or the address is locked
+	if err := s.checkLockout(ctx, loginAccountKey(req.Username), loginIPKey(req.ClientIP)); err != nil {
+		return nil, err
+	}
+
 	// Get user information from the database
 	userInfo, err := s.querier.GetUserInfoFromUsername(ctx, req.Username)
 	if err != nil {
+		// Unknown usernames count too, so they cannot be told apart by the lockout
+		if err := s.loginFailed(ctx, req, nil); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("invalid username")
 	}
 
 	// Check if the password matches using bcrypt
 	err = s.pwdManager.ValidatePassword(userInfo.PasswordHash, req.Password)
 	if err != nil {
+		if err := s.loginFailed(ctx, req, &userInfo); err != nil {
+			return nil, err
+		}
 		return nil, fmt.Errorf("your password is incorrect, please try again")
 	}
 
+	// The failures of the account start over after a successful login
+	if err := s.cacher.ClearFailedAttempts(ctx, loginAccountKey(req.Username)); err != nil {
+		log.Printf("failed to clear failed logins: %v", err)
+	}
+
 	tokenString, err := s.jwtGenerator.GenerateToken(userInfo.UserID, userInfo.Username)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
@@ -77,29 +105,11 @@ func (s *UserService) UserLogin(ctx context.Context, req model.LoginRequest) (*m
 }
 
 func (s *UserService) UserRegister(ctx context.Context, req model.RegisterRequest) error {
-	// Validate the email code from cacher
-	email, err := s.cacher.GetEmailUsingCode(ctx, req.EmailCode)
-	if err != nil {
+	// Validate the email code sent to the email
+	if err := s.verifyEmailCode(ctx, model.EmailCodeRegister, req.EmailCode, req.Email, req.ClientIP); err != nil {
 		return err
 	}
 
-	// If email code is nil, it means the code is invalid or expired
-	if email == nil {
-		return fmt.Errorf("invalid or expired email code")
-	}
-
-	// Code exists, defer deletion of the email code from cacher
-	defer func() {
-		if err := s.cacher.DeleteEmailCode(ctx, req.EmailCode); err != nil {
-			log.Printf("failed to delete email code: %v", err)
-		}
-	}()
-
-	// Check if the email matches the one stored in the cacher
-	if *email != req.Email {
-		return fmt.Errorf("invalid email code")
-	}
-
 	// Check if the username or email already exists
 	isAvailable, err := s.querier.IsNewUserAvailable(ctx, repo.IsNewUserAvailableParams{
 		Username: req.Username,
@@ -128,6 +138,7 @@ func (s *UserService) UserRegister(ctx context.Context, req model.RegisterReques
 		Username:     req.Username,
 		PasswordHash: hashedPassword,
 		Email:        req.Email,
+		Language:     s.mailer.MatchLanguage(req.Language),
 	}
 
 	err = s.querier.CreateNewUser(ctx, newUserInfo)
@@ -140,9 +151,8 @@ func (s *UserService) UserRegister(ctx context.Context, req model.RegisterReques
 }
 
 func (s *UserService) GetEmailCode(ctx context.Context, req model.GetEmailCodeRequest) error {
-	// Try to generate a unique email code, with a maximum of 10 attempts
-	emailCode, err := s.tryGenerateEmailCode(ctx, 10)
-	if err != nil {
+	// Only one code can be requested per email within the cooldown
+	if err := s.checkEmailCodeCooldown(ctx, req.Email); err != nil {
 		return err
 	}
 
@@ -152,49 +162,50 @@ func (s *UserService) GetEmailCode(ctx context.Context, req model.GetEmailCodeRe
 		return err
 	}
 
-	// If the email already exists, add username to the message
-	var message string
+	// If the email already exists, greet the user in their language
+	data := mailer.EmailCodeData{}
+	language := req.Language
 	if err == nil {
-		message += fmt.Sprintf("<p>Hello <b>%s</b>,</p>", existingUser.Username)
+		data.Username = existingUser.Username
+		language = existingUser.Language
+	}
+	template := mailer.TemplateEmailCodeRegister
+	if req.Purpose == model.EmailCodeResetPassword {
+		template = mailer.TemplateEmailCodeResetPassword
 	}
-	message += fmt.Sprintf("Your email verification code is: %s", *emailCode)
-
-	// Send the email code to the user's email address
-	s.mailer.SendEmail(req.Email, "Your Email verification code", message)
 
-	// Store the email code in the cacher with an expiration time
-	err = s.cacher.StoreCodeAndEmail(ctx, *emailCode, req.Email)
+	data.Code, err = s.issueEmailCode(ctx, req.Purpose, req.Email)
 	if err != nil {
 		return err
 	}
+	data.ExpiresAt = s.emailCodeExpiresAt()
 
-	// Finally, return nil to indicate success
-	return nil
+	// Send the email code to the user's email address
+	if err := s.mailer.SendTemplate(ctx, req.Email, template, language, data); err != nil {
+		return err
+	}
+
+	// The cooldown only starts once the email is queued, so that failures can be retried right away
+	return s.cacher.StartEmailCodeCooldown(ctx, req.Email)
 }
 
-// ResetPassword handles the password reset request
-func (s *UserService) ResetPassword(ctx context.Context, req model.ResetPasswordRequest) error {
-	// Validate the email code from cacher
-	email, err := s.cacher.GetEmailUsingCode(ctx, req.EmailCode)
+// checkEmailCodeCooldown returns an EmailCodeCooldownError if a code was sent to the email recently
+func (s *UserService) checkEmailCodeCooldown(ctx context.Context, email string) error {
+	cooldown, err := s.cacher.GetEmailCodeCooldown(ctx, email)
 	if err != nil {
 		return err
 	}
-
-	// If email code is nil, it means the code is invalid or expired
-	if email == nil {
-		return fmt.Errorf("invalid or expired email code")
+	if cooldown > 0 {
+		return &EmailCodeCooldownError{RetryAfter: cooldown}
 	}
+	return nil
+}
 
-	// Code exists, defer deletion of the email code from cacher
-	defer func() {
-		if err := s.cacher.DeleteEmailCode(ctx, req.EmailCode); err != nil {
-			log.Printf("failed to delete email code: %v", err)
-		}
-	}()
-
-	// Check if the email matches the one stored in the cacher
-	if *email != req.Email {
-		return fmt.Errorf("invalid email code")
+// ResetPassword handles the password reset request
+func (s *UserService) ResetPassword(ctx context.Context, req model.ResetPasswordRequest) error {
+	// Validate the email code sent to the email
+	if err := s.verifyEmailCode(ctx, model.EmailCodeResetPassword, req.EmailCode, req.Email, req.ClientIP); err != nil {
+		return err
 	}
 
 	// Hash the new password using bcrypt
@@ -221,37 +232,44 @@ func (s *UserService) ResetPassword(ctx context.Context, req model.ResetPassword
 	return nil
 }
 
-// tryGenerateEmailCode attempts to generate a unique 6-digit email code
-// If the code already exists, it will retry up to `tryCount` times
-// Returns the generated email code or an error if it fails after multiple attempts
-func (s *UserService) tryGenerateEmailCode(ctx context.Context, tryCount int) (*string, error) {
-	// Generate a random 6-digit email code
-	if tryCount == 0 {
-		return nil, fmt.Errorf("failed to generate email code after multiple attempts")
+// issueEmailCode generates a code for the email and the purpose, replacing the previous one
+func (s *UserService) issueEmailCode(ctx context.Context, purpose string, email string) (string, error) {
+	emailCode, err := generateEmailCode()
+	if err != nil {
+		return "", err
+	}
+
+	// Store the email code in the cacher with an expiration time
+	if err := s.cacher.StoreEmailCode(ctx, purpose, email, emailCode); err != nil {
+		return "", err
+	}
+	// The new code gets a fresh number of tries
+	if err := s.cacher.ClearFailedAttempts(ctx, emailCodeEmailKey(purpose, email)); err != nil {
+		return "", err
+	}
+
+	return emailCode, nil
+}
+
+// emailCodeExpiresAt returns when codes issued now expire, zero if they do not
+func (s *UserService) emailCodeExpiresAt() time.Time {
+	if s.emailCodeExpiration <= 0 {
+		return time.Time{}
 	}
+	return time.Now().Add(s.emailCodeExpiration)
+}
 
+// generateEmailCode generates a random 6-digit email code
+func generateEmailCode() (string, error) {
 	emailCode := make([]byte, 6)
 	for i := range emailCode {
 		num, err := rand.Int(rand.Reader, big.NewInt(10))
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate email code: %w", err)
+			return "", fmt.Errorf("failed to generate email code: %w", err)
 		}
 		// Convert the number to a byte and store it in the emailCode slice
 		emailCode[i] = '0' + byte(num.Int64())
 	}
 
-	// Check if the email code already exists in the cacher
-	existingEmail, err := s.cacher.GetEmailUsingCode(ctx, string(emailCode))
-	if err != nil {
-		return nil, fmt.Errorf("failed to check existing email code: %w", err)
-	}
-
-	// If the email code already exists, try generating a new one
-	if existingEmail != nil {
-		return s.tryGenerateEmailCode(ctx, tryCount-1)
-	}
-
-	// If the email code is unique, return it
-	emailCodeStr := string(emailCode)
-	return &emailCodeStr, nil
+	return string(emailCode), nil
 }
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/ZureTz/shorter-url/app"
+import (
+	"os"
+
+	"github.com/ZureTz/shorter-url/app"
+)
 
 func main() {
 	// Initialize the application
@@ -8,5 +12,14 @@ func main() {
 	if err := app.Init("config.toml"); err != nil {
 		panic(err)
 	}
+
+	// Run an administrative command if one is given, e.g. `shorter-url warmup`
+	if len(os.Args) > 1 {
+		if err := app.RunCommand(os.Args[1]); err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	app.Run()
 }
@@ -0,0 +1,54 @@
+package geoip
+
+import (
+	"net"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Country is where an IP address is located
+// ISOCode is empty if the address is not in the database
+type Country struct {
+	ISOCode           string
+	IsInEuropeanUnion bool
+}
+
+// record is the part of a GeoIP2/GeoLite2 country or city record that is read
+type record struct {
+	Country struct {
+		ISOCode           string `maxminddb:"iso_code"`
+		IsInEuropeanUnion bool   `maxminddb:"is_in_european_union"`
+	} `maxminddb:"country"`
+}
+
+// Locator looks up the country of IP addresses in an offline MaxMind-format database
+type Locator struct {
+	reader *maxminddb.Reader
+}
+
+// NewLocator opens the database file given in the config
+func NewLocator(c config.GeoIPConfig) (*Locator, error) {
+	reader, err := maxminddb.Open(c.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+	return &Locator{reader: reader}, nil
+}
+
+// LookupCountry finds the country of the IP address
+func (l *Locator) LookupCountry(ip net.IP) (Country, error) {
+	var r record
+	if err := l.reader.Lookup(ip, &r); err != nil {
+		return Country{}, err
+	}
+	return Country{
+		ISOCode:           r.Country.ISOCode,
+		IsInEuropeanUnion: r.Country.IsInEuropeanUnion,
+	}, nil
+}
+
+// Close releases the database file
+func (l *Locator) Close() error {
+	return l.reader.Close()
+}
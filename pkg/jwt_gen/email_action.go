@@ -0,0 +1,78 @@
+package jwt_gen
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Actions of the links in emails
+const (
+	ActionExtendLink                 = "extend_link"
+	ActionUnsubscribeExpiryReminders = "unsubscribe_expiry_reminders"
+)
+
+// How long the tokens work if no expiration is configured
+const defaultEmailActionExpiration = 7 * 24 * time.Hour
+
+var ErrWrongEmailAction = errors.New("token is for another action")
+
+// EmailActionClaims authorize a single action from a link in an email, without logging in
+type EmailActionClaims struct {
+	Action string `json:"act"`
+	UserID string `json:"uid"`
+	// The link to extend and its expiry when the email was sent, in microseconds since the epoch
+	// Once the expiry changed the token no longer works, so it cannot be used twice
+	URLID     int64 `json:"url,omitempty"`
+	ExpiredAt int64 `json:"lexp,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// EmailActionSigner signs and verifies the tokens of the links in emails
+// The key is derived from the secret key, so the tokens cannot be used to log in
+type EmailActionSigner struct {
+	key        []byte
+	expiration time.Duration
+}
+
+func NewEmailActionSigner(c config.AuthConfig, expiration time.Duration) *EmailActionSigner {
+	if expiration <= 0 {
+		expiration = defaultEmailActionExpiration
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.SecretKey))
+	mac.Write([]byte("email_action"))
+
+	return &EmailActionSigner{
+		key:        mac.Sum(nil),
+		expiration: expiration,
+	}
+}
+
+// Sign returns the token of the claims, expiring after the configured time
+func (s *EmailActionSigner) Sign(claims EmailActionClaims) (string, error) {
+	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(s.expiration))
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.key)
+}
+
+// Parse verifies the token and returns its claims if it is for the given action
+func (s *EmailActionSigner) Parse(action string, tokenString string) (*EmailActionClaims, error) {
+	claims := &EmailActionClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Action != action {
+		return nil, ErrWrongEmailAction
+	}
+	return claims, nil
+}
@@ -0,0 +1,113 @@
+package jwt_gen
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testAuthConfig = config.AuthConfig{SecretKey: "test_secret_key", JWTExpiration: time.Hour}
+
+func TestEmailActionSignAndParse(t *testing.T) {
+	signer := NewEmailActionSigner(testAuthConfig, time.Hour)
+
+	token, err := signer.Sign(EmailActionClaims{
+		Action:    ActionExtendLink,
+		UserID:    "user-1",
+		URLID:     42,
+		ExpiredAt: 1767225600000000,
+	})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	claims, err := signer.Parse(ActionExtendLink, token)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if claims.Action != ActionExtendLink || claims.UserID != "user-1" || claims.URLID != 42 || claims.ExpiredAt != 1767225600000000 {
+		t.Errorf("Parse() = %+v, want the signed claims", claims)
+	}
+	if expiresIn := time.Until(claims.ExpiresAt.Time); expiresIn <= 59*time.Minute || expiresIn > time.Hour {
+		t.Errorf("token expires in %v, want an hour", expiresIn)
+	}
+}
+
+func TestEmailActionParseBindsAction(t *testing.T) {
+	signer := NewEmailActionSigner(testAuthConfig, time.Hour)
+
+	token, err := signer.Sign(EmailActionClaims{Action: ActionUnsubscribeExpiryReminders, UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// An unsubscribe link cannot be used to extend a link, and the other way around
+	if _, err := signer.Parse(ActionExtendLink, token); !errors.Is(err, ErrWrongEmailAction) {
+		t.Errorf("Parse() error = %v, want ErrWrongEmailAction", err)
+	}
+	if _, err := signer.Parse(ActionUnsubscribeExpiryReminders, token); err != nil {
+		t.Errorf("Parse() error = %v, want nil", err)
+	}
+}
+
+func TestEmailActionRejectsOtherTokens(t *testing.T) {
+	signer := NewEmailActionSigner(testAuthConfig, time.Hour)
+	valid := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	sign := func(t *testing.T, method jwt.SigningMethod, key []byte, claims jwt.Claims) string {
+		t.Helper()
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	// Login tokens are signed with the secret key itself
+	loginToken, err := NewJWTGenerator(testAuthConfig).GenerateToken("user-1", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner := NewEmailActionSigner(config.AuthConfig{SecretKey: "other_secret_key"}, time.Hour)
+	otherToken, err := otherSigner.Sign(EmailActionClaims{Action: ActionExtendLink, UserID: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "login token", token: loginToken},
+		{name: "signed with the secret key", token: sign(t, jwt.SigningMethodHS256, []byte(testAuthConfig.SecretKey), &EmailActionClaims{
+			Action: ActionExtendLink, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: valid},
+		})},
+		{name: "other secret key", token: otherToken},
+		{name: "expired", token: sign(t, jwt.SigningMethodHS256, signer.key, &EmailActionClaims{
+			Action: ActionExtendLink, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+		})},
+		{name: "without expiry", token: sign(t, jwt.SigningMethodHS256, signer.key, &EmailActionClaims{Action: ActionExtendLink})},
+		{name: "other signing method", token: sign(t, jwt.SigningMethodHS512, signer.key, &EmailActionClaims{
+			Action: ActionExtendLink, RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: valid},
+		})},
+		{name: "malformed", token: "not.a.token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Parse(ActionExtendLink, tt.token); err == nil {
+				t.Error("Parse() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestNewEmailActionSignerDefaultExpiration(t *testing.T) {
+	signer := NewEmailActionSigner(testAuthConfig, 0)
+	if signer.expiration != defaultEmailActionExpiration {
+		t.Errorf("expiration = %v, want %v", signer.expiration, defaultEmailActionExpiration)
+	}
+}
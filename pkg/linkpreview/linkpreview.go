@@ -0,0 +1,216 @@
+package linkpreview
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// Longest text kept for each field, longer values are truncated
+const maxFieldLength = 1000
+
+// Metadata describes the page behind a link
+type Metadata struct {
+	Title         string
+	Description   string
+	OGTitle       string
+	OGDescription string
+	OGImage       string
+	OGSiteName    string
+	FaviconURL    string
+}
+
+// Fetcher fetches the metadata of web pages
+type Fetcher struct {
+	client    *http.Client
+	maxBytes  int64
+	userAgent string
+}
+
+// NewFetcher creates a fetcher reading at most maxBytes of every page
+// The client should refuse to connect to internal addresses, see the safehttp package
+func NewFetcher(client *http.Client, maxBytes int64, userAgent string) *Fetcher {
+	return &Fetcher{
+		client:    client,
+		maxBytes:  maxBytes,
+		userAgent: userAgent,
+	}
+}
+
+// Fetch downloads the page and extracts its title, description, Open Graph tags and favicon
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("page responded with status %d", resp.StatusCode)
+	}
+
+	// Relative links are resolved against the final URL, after redirects
+	pageURL := resp.Request.URL
+	metadata := &Metadata{FaviconURL: resolveURL(pageURL, "/favicon.ico")}
+
+	// Only HTML pages carry metadata
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return metadata, nil
+	}
+
+	// Pages in other encodings like GBK or Latin-1 are decoded to UTF-8
+	// The charset is taken from the Content-Type header, a byte order mark or a meta tag
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBytes), resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page: %w", err)
+	}
+
+	parseHead(body, pageURL, metadata)
+	return metadata, nil
+}
+
+// parseHead reads the metadata from the tags of the page, stopping at the end of the head
+func parseHead(r io.Reader, pageURL *url.URL, metadata *Metadata) {
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// End of the document, or of the allowed size
+			return
+		case html.TextToken:
+			if inTitle && metadata.Title == "" {
+				metadata.Title = clean(string(tokenizer.Text()))
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttributes := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = true
+			case "body":
+				return
+			case "meta":
+				if hasAttributes {
+					parseMeta(attributes(tokenizer), pageURL, metadata)
+				}
+			case "link":
+				if hasAttributes {
+					parseLink(attributes(tokenizer), pageURL, metadata)
+				}
+			}
+		}
+	}
+}
+
+func parseMeta(attrs map[string]string, pageURL *url.URL, metadata *Metadata) {
+	content := clean(attrs["content"])
+	if content == "" {
+		return
+	}
+
+	// Open Graph uses the property attribute, though many pages use name instead
+	key := strings.ToLower(attrs["property"])
+	if key == "" {
+		key = strings.ToLower(attrs["name"])
+	}
+
+	switch key {
+	case "description":
+		setOnce(&metadata.Description, content)
+	case "og:title":
+		setOnce(&metadata.OGTitle, content)
+	case "og:description":
+		setOnce(&metadata.OGDescription, content)
+	case "og:image", "og:image:url", "og:image:secure_url":
+		setOnce(&metadata.OGImage, resolveURL(pageURL, content))
+	case "og:site_name":
+		setOnce(&metadata.OGSiteName, content)
+	}
+}
+
+// parseLink replaces the default favicon with the first icon declared by the page
+func parseLink(attrs map[string]string, pageURL *url.URL, metadata *Metadata) {
+	href := strings.TrimSpace(attrs["href"])
+	if href == "" {
+		return
+	}
+
+	for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+		if rel == "icon" || rel == "apple-touch-icon" {
+			if metadata.FaviconURL == resolveURL(pageURL, "/favicon.ico") {
+				metadata.FaviconURL = resolveURL(pageURL, href)
+			}
+			return
+		}
+	}
+}
+
+// attributes collects the attributes of the current tag with lowercase keys
+func attributes(tokenizer *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		key, value, more := tokenizer.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(value)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+func setOnce(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// resolveURL resolves the reference against the page, keeping only http(s) URLs
+func resolveURL(pageURL *url.URL, ref string) string {
+	resolved, err := pageURL.Parse(strings.TrimSpace(ref))
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+		return ""
+	}
+	return truncate(resolved.String())
+}
+
+// clean collapses whitespace, drops what cannot be stored as text and truncates overly long values
+func clean(value string) string {
+	// Pages may declare the wrong charset, and text columns reject invalid UTF-8 and NUL characters
+	value = strings.ReplaceAll(strings.ToValidUTF8(value, ""), "\x00", "")
+	return truncate(strings.Join(strings.Fields(value), " "))
+}
+
+func truncate(value string) string {
+	if len(value) <= maxFieldLength {
+		return value
+	}
+	value = value[:maxFieldLength]
+	// Do not cut a multi-byte character in half
+	for !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,60 @@
+package linkpreview
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchDecodesCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantTitle   string
+	}{
+		{
+			name:        "utf-8",
+			contentType: "text/html; charset=utf-8",
+			body:        "<html><head><title>Café</title></head></html>",
+			wantTitle:   "Café",
+		},
+		{
+			name:        "latin-1 from the header",
+			contentType: "text/html; charset=iso-8859-1",
+			body:        "<html><head><title>Caf\xe9</title></head></html>",
+			wantTitle:   "Café",
+		},
+		{
+			name:        "gbk from the meta tag",
+			contentType: "text/html",
+			body:        `<html><head><meta charset="gbk"><title>` + "\xd6\xd0\xce\xc4" + `</title></head></html>`,
+			wantTitle:   "中文",
+		},
+		{
+			name:        "wrong charset replaces invalid characters",
+			contentType: "text/html; charset=utf-8",
+			body:        "<html><head><title>Caf\xe9\x00 au lait</title></head></html>",
+			wantTitle:   "Caf\ufffd\ufffd au lait",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			metadata, err := NewFetcher(server.Client(), 1<<20, "test").Fetch(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if metadata.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", metadata.Title, tt.wantTitle)
+			}
+		})
+	}
+}
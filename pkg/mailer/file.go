@@ -0,0 +1,94 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+// Formats of the files written by the file transport
+const (
+	FileFormatEML     = "eml"
+	FileFormatMaildir = "maildir"
+)
+
+// FileTransport writes emails to a directory instead of sending them, for development and tests
+// Emails are written as .eml files, or delivered to a maildir that mail clients can open
+type FileTransport struct {
+	fromMail string
+	dir      string
+	format   string
+
+	// Keeps the names of files written in the same nanosecond unique
+	counter atomic.Uint64
+}
+
+func NewFileTransport(c config.MailerConfig) (*FileTransport, error) {
+	t := &FileTransport{
+		fromMail: c.FromMail,
+		dir:      c.FileDir,
+		format:   c.FileFormat,
+	}
+	if t.format == "" {
+		t.format = FileFormatEML
+	}
+
+	switch t.format {
+	case FileFormatEML:
+		if err := os.MkdirAll(t.dir, 0o755); err != nil {
+			return nil, err
+		}
+	case FileFormatMaildir:
+		for _, sub := range []string{"tmp", "new", "cur"} {
+			if err := os.MkdirAll(filepath.Join(t.dir, sub), 0o755); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown mail file format: %q", t.format)
+	}
+
+	return t, nil
+}
+
+// Send writes the email as a MIME message
+func (t *FileTransport) Send(msg *Message) error {
+	name := fmt.Sprintf("%d.%d_%d", time.Now().UnixNano(), os.Getpid(), t.counter.Add(1))
+
+	if t.format == FileFormatEML {
+		return t.write(filepath.Join(t.dir, name+".eml"), msg)
+	}
+
+	// Maildir readers only see complete files, which are written to tmp first and then moved to new
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	name += "." + hostname
+	tmpPath := filepath.Join(t.dir, "tmp", name)
+	if err := t.write(tmpPath, msg); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(t.dir, "new", name))
+}
+
+func (t *FileTransport) write(path string, msg *Message) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := msg.mime(t.fromMail).WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (t *FileTransport) Close() error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+// HTTPTransport posts emails as JSON to the API of an email provider, or to a local stub
+type HTTPTransport struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+	fromMail string
+}
+
+// httpMessage is the body posted to the endpoint
+type httpMessage struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+	// Extra headers of the email, e.g. List-Unsubscribe
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func NewHTTPTransport(c config.MailerConfig) (*HTTPTransport, error) {
+	if c.HTTPEndpoint == "" {
+		return nil, errors.New("the http mail transport needs an endpoint")
+	}
+
+	timeout := c.HTTPTimeout
+	if timeout <= 0 {
+		timeout = defaultTransportTimeout
+	}
+
+	return &HTTPTransport{
+		client:   &http.Client{Timeout: timeout},
+		endpoint: c.HTTPEndpoint,
+		apiKey:   c.HTTPAPIKey,
+		fromMail: c.FromMail,
+	}, nil
+}
+
+// Send posts the email, any 2xx response counts as sent
+func (t *HTTPTransport) Send(msg *Message) error {
+	var headers map[string]string
+	if msg.ListUnsubscribe != "" {
+		headers = map[string]string{
+			"List-Unsubscribe":      "<" + msg.ListUnsubscribe + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+
+	body, err := json.Marshal(httpMessage{
+		From:    t.fromMail,
+		To:      msg.To,
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		HTML:    msg.HTML,
+		Headers: headers,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil
+	}
+
+	// Keep the start of the response, providers explain the error there
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("mail api responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	// The provider refused the email itself, rather than being unavailable or misconfigured
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity {
+		return &permanentError{err: err}
+	}
+	return err
+}
+
+func (t *HTTPTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,26 @@
+package mailer
+
+import (
+	"log"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+// LogTransport prints emails to the log instead of sending them, for development
+type LogTransport struct {
+	fromMail string
+}
+
+func NewLogTransport(c config.MailerConfig) *LogTransport {
+	return &LogTransport{fromMail: c.FromMail}
+}
+
+// Send prints the plain text version of the email
+func (t *LogTransport) Send(msg *Message) error {
+	log.Printf("Email from %s to %s: %s\n%s", t.fromMail, msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+func (t *LogTransport) Close() error {
+	return nil
+}
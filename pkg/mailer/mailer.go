@@ -1,50 +1,49 @@
 package mailer
 
 import (
-	"fmt"
-	"log"
-
 	"github.com/ZureTz/shorter-url/config"
-	"gopkg.in/gomail.v2"
 )
 
+// Mailer renders the emails sent to users from the templates
 type Mailer struct {
-	dialer      gomail.Dialer
-	fromMail    string
-	mailChannel chan *gomail.Message // Buffered channel for email messages
+	templates *Templates
 }
 
-func NewMailer(c config.MailerConfig) *Mailer {
-	dialer := gomail.NewDialer(c.SMTPHost, c.SMTPPort, c.Username, c.Password)
-	mailer := &Mailer{
-		dialer:      *dialer,
-		fromMail:    c.FromMail,
-		mailChannel: make(chan *gomail.Message, 100),
+func NewMailer(c config.MailerConfig) (*Mailer, error) {
+	// Load the email templates, the directory overrides the embedded ones
+	templates, err := LoadTemplates(c.TemplateDir, c.DefaultLanguage)
+	if err != nil {
+		return nil, err
 	}
-	// Start the mailer daemon
-	go mailer.MailerDaemon()
 
-	return mailer
+	return &Mailer{
+		templates: templates,
+	}, nil
 }
 
-func (m *Mailer) MailerDaemon() {
-	for message := range m.mailChannel {
-		if err := m.dialer.DialAndSend(message); err != nil {
-			log.Print(err)
-		}
+// Render renders the template in the language as an email to the recipient
+func (m *Mailer) Render(to string, template string, language string, data any) (*Message, error) {
+	subject, text, html, err := m.templates.Render(template, language, data)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (m *Mailer) SendEmail(to string, subject string, body string) {
-	msg := gomail.NewMessage()
-	msg.SetHeader("From", m.fromMail)
-	msg.SetHeader("To", to)
-	msg.SetHeader("Subject", subject)
-	msg.SetBody("text/html", fmt.Sprintf("<p>%s</p>", body))
 
-	m.mailChannel <- msg // Send the message to the channel
+	msg := &Message{
+		To:      to,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	}
+	if unsubscriber, ok := data.(listUnsubscriber); ok {
+		msg.ListUnsubscribe = unsubscriber.ListUnsubscribeURL()
+	}
+	if expirer, ok := data.(expirer); ok {
+		msg.ExpiresAt = expirer.MailExpiresAt()
+	}
+	return msg, nil
 }
 
-func (m *Mailer) StopDaemon() {
-	close(m.mailChannel) // Close the channel to stop the daemon
+// MatchLanguage returns the language of the emails sent to someone preferring the languages
+func (m *Mailer) MatchLanguage(preferred string) string {
+	return m.templates.MatchLanguage(preferred)
 }
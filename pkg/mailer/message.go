@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"gopkg.in/gomail.v2"
+)
+
+// Message is a rendered email with a plain text and an HTML body
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Address unsubscribing the recipient with a POST, sent as the List-Unsubscribe header (RFC 8058)
+	ListUnsubscribe string
+	// Emails not sent by then are dropped rather than sent late, zero if they do not expire
+	ExpiresAt time.Time
+}
+
+// listUnsubscriber is implemented by the data of emails the recipient can unsubscribe from
+type listUnsubscriber interface {
+	ListUnsubscribeURL() string
+}
+
+// expirer is implemented by the data of emails that are useless after a while, like email codes
+type expirer interface {
+	MailExpiresAt() time.Time
+}
+
+// mime builds the multipart text and HTML message sent from the address
+func (msg *Message) mime(from string) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", from)
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+	if msg.ListUnsubscribe != "" {
+		m.SetHeader("List-Unsubscribe", "<"+msg.ListUnsubscribe+">")
+		m.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	// Clients show the last alternative they support, so the HTML body comes last
+	m.SetBody("text/plain", msg.Text)
+	m.AddAlternative("text/html", msg.HTML)
+	return m
+}
+
+// Used by the smtp and http transports if their timeout is not set, so that a hung server cannot stall the mail queue
+const defaultTransportTimeout = 10 * time.Second
+
+// Transport delivers rendered emails
+type Transport interface {
+	Send(msg *Message) error
+	// Close releases the connection of the transport, the next Send opens a new one
+	Close() error
+}
+
+// NewTransport creates the transport selected by the config
+func NewTransport(c config.MailerConfig) (Transport, error) {
+	switch c.Transport {
+	case "", "smtp":
+		return NewSMTPTransport(c)
+	case "log":
+		return NewLogTransport(c), nil
+	case "file":
+		return NewFileTransport(c)
+	case "http":
+		return NewHTTPTransport(c)
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %q", c.Transport)
+	}
+}
@@ -0,0 +1,241 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+	"gopkg.in/gomail.v2"
+)
+
+// SMTPTransport sends emails through an SMTP server, reusing the connection between emails
+type SMTPTransport struct {
+	host      string
+	port      int
+	username  string
+	password  string
+	ssl       bool
+	tlsConfig *tls.Config
+	fromMail  string
+	// Deadline of the connection for dialing and for each email, so that a stalled server cannot block the mail queue
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	client *smtp.Client
+}
+
+// NewSMTPTransport creates a transport for the SMTP server of the config, it connects on the first email
+func NewSMTPTransport(c config.MailerConfig) (*SMTPTransport, error) {
+	t := &SMTPTransport{
+		host:     c.SMTPHost,
+		port:     c.SMTPPort,
+		username: c.Username,
+		password: c.Password,
+		fromMail: c.FromMail,
+		timeout:  c.SMTPTimeout,
+		// Skipping the verification is only meant for development servers with self-signed certificates
+		tlsConfig: &tls.Config{
+			ServerName:         c.SMTPHost,
+			InsecureSkipVerify: c.SMTPInsecureSkipVerify,
+		},
+	}
+	switch c.SMTPSecurity {
+	case "", "starttls":
+		t.ssl = false
+	case "tls":
+		t.ssl = true
+	default:
+		return nil, fmt.Errorf("unknown smtp security: %q", c.SMTPSecurity)
+	}
+	if t.timeout <= 0 {
+		t.timeout = defaultTransportTimeout
+	}
+	return t, nil
+}
+
+// Send sends the email as a multipart text and HTML message
+func (t *SMTPTransport) Send(msg *Message) error {
+	m := msg.mime(t.fromMail)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The server may have closed an idle connection, in which case it is opened again once
+	for retried := false; ; retried = true {
+		if t.client == nil {
+			if err := t.dial(); err != nil {
+				return err
+			}
+		}
+
+		err := t.send(msg.To, m)
+		if err == nil || IsPermanent(err) || retried {
+			return err
+		}
+		t.client.Close()
+		t.conn, t.client = nil, nil
+	}
+}
+
+// dial connects and authenticates to the server, within the timeout
+func (t *SMTPTransport) dial() error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(t.host, strconv.Itoa(t.port)), t.timeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(t.timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	if t.ssl {
+		conn = tls.Client(conn, t.tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, t.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !t.ssl {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(t.tlsConfig); err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+	if t.username != "" {
+		if ok, auths := client.Extension("AUTH"); ok {
+			if err := client.Auth(t.auth(auths)); err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+
+	t.conn, t.client = conn, client
+	return nil
+}
+
+// auth picks the mechanism offered by the server, preferring CRAM-MD5 and PLAIN over LOGIN
+func (t *SMTPTransport) auth(mechanisms string) smtp.Auth {
+	switch {
+	case strings.Contains(mechanisms, "CRAM-MD5"):
+		return smtp.CRAMMD5Auth(t.username, t.password)
+	case strings.Contains(mechanisms, "LOGIN") && !strings.Contains(mechanisms, "PLAIN"):
+		return &loginAuth{username: t.username, password: t.password, host: t.host}
+	default:
+		return smtp.PlainAuth("", t.username, t.password, t.host)
+	}
+}
+
+// send sends one email over the open connection, which gets a new deadline for it
+func (t *SMTPTransport) send(to string, m *gomail.Message) error {
+	if err := t.conn.SetDeadline(time.Now().Add(t.timeout)); err != nil {
+		return err
+	}
+
+	// The SMTP errors are returned as they are, so that IsPermanent can read their codes
+	if err := t.client.Mail(t.fromMail); err != nil {
+		return err
+	}
+	if err := t.client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := t.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := m.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Close closes the connection to the SMTP server
+func (t *SMTPTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client == nil {
+		return nil
+	}
+	// Quit waits for the server, so it is bounded by the timeout as well
+	t.conn.SetDeadline(time.Now().Add(t.timeout))
+	err := t.client.Quit()
+	if err != nil {
+		t.client.Close()
+	}
+	t.conn, t.client = nil, nil
+	return err
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not provide
+// Like smtp.PlainAuth it only sends the password over TLS or to localhost
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch {
+	case bytes.EqualFold(fromServer, []byte("Username:")):
+		return []byte(a.username), nil
+	case bytes.EqualFold(fromServer, []byte("Password:")):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge: %s", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// permanentError marks errors after which sending the email again cannot succeed
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// IsPermanent reports whether the email was rejected, in which case sending it again cannot succeed
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return true
+	}
+
+	var smtpErr *textproto.Error
+	if !errors.As(err, &smtpErr) {
+		return false
+	}
+	// Mailbox unavailable, user not local, storage exceeded and mailbox name not allowed
+	return smtpErr.Code >= 550 && smtpErr.Code <= 553
+}
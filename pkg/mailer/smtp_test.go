@@ -0,0 +1,90 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+// stallingSMTPServer greets and answers EHLO, then never answers the next command
+func stallingSMTPServer(t *testing.T) (string, int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				conn.Write([]byte("220 localhost ESMTP\r\n"))
+				if _, err := reader.ReadString('\n'); err != nil {
+					return
+				}
+				conn.Write([]byte("250 localhost\r\n"))
+				// Read the remaining commands without answering them
+				for {
+					if _, err := reader.ReadString('\n'); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber
+}
+
+func TestSMTPTransportSendTimesOut(t *testing.T) {
+	host, port := stallingSMTPServer(t)
+	transport, err := NewSMTPTransport(config.MailerConfig{
+		FromMail:    "from@example.com",
+		SMTPHost:    host,
+		SMTPPort:    port,
+		SMTPTimeout: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- transport.Send(&Message{To: "to@example.com", Subject: "Hello", Text: "text", HTML: "<p>html</p>"})
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Send succeeded against a stalled server")
+		}
+		if IsPermanent(err) {
+			t.Errorf("IsPermanent(%v) = true, a timeout should be retried", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send did not return after the timeout")
+	}
+}
+
+func TestNewSMTPTransportDefaultTimeout(t *testing.T) {
+	transport, err := NewSMTPTransport(config.MailerConfig{SMTPHost: "smtp.example.com", SMTPPort: 587})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.timeout != defaultTransportTimeout {
+		t.Errorf("timeout = %v, want %v", transport.timeout, defaultTransportTimeout)
+	}
+}
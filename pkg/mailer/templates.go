@@ -0,0 +1,268 @@
+package mailer
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/ZureTz/shorter-url/pkg/visitor"
+)
+
+// Names of the templates, each one has a .txt file with the subject and the plain text body, and a .html file
+const (
+	TemplateEmailCodeRegister      = "email_code_register"
+	TemplateEmailCodeResetPassword = "email_code_reset_password"
+	// Sent to the current and the new address when changing the email of an account
+	TemplateEmailCodeChangeEmailCurrent = "email_code_change_email_current"
+	TemplateEmailCodeChangeEmailNew     = "email_code_change_email_new"
+	TemplateAccountLocked               = "account_locked"
+	TemplateLinkBroken                  = "link_broken"
+	TemplateLinkExpiring                = "link_expiring"
+)
+
+// Data of the templates
+
+type EmailCodeData struct {
+	// Empty if no account uses the email yet
+	Username string
+	Code     string
+	// When the code expires, the email is not sent after that
+	ExpiresAt time.Time
+}
+
+func (d EmailCodeData) MailExpiresAt() time.Time {
+	return d.ExpiresAt
+}
+
+type EmailChangeCodeData struct {
+	Username  string
+	Code      string
+	NewEmail  string
+	ExpiresAt time.Time
+}
+
+func (d EmailChangeCodeData) MailExpiresAt() time.Time {
+	return d.ExpiresAt
+}
+
+type AccountLockedData struct {
+	Username    string
+	Failures    int
+	ClientIP    string
+	LockedUntil time.Time
+}
+
+type LinkBrokenData struct {
+	Username    string
+	ShortCode   string
+	OriginalURL string
+	// Status of the last check, or the error if the destination could not be reached
+	StatusCode int
+	Error      string
+}
+
+type ExpiringLink struct {
+	ShortCode   string
+	ShortURL    string
+	OriginalURL string
+	ExpiredAt   time.Time
+	// Extends the expiry of the link in one click
+	ExtendURL string
+}
+
+type LinkExpiringData struct {
+	Username string
+	Links    []ExpiringLink
+	// Turns off these emails in one click
+	UnsubscribeURL string
+}
+
+func (d LinkExpiringData) ListUnsubscribeURL() string {
+	return d.UnsubscribeURL
+}
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Templates renders localized emails from the embedded templates, which files in the template directory override
+// The layout.html file wraps the "content" template of every HTML body
+type Templates struct {
+	defaultLanguage string
+	languages       []string
+	text            map[string]*texttemplate.Template
+	html            map[string]*htmltemplate.Template
+}
+
+// LoadTemplates parses the templates of every language, the template directory may add languages
+func LoadTemplates(dir string, defaultLanguage string) (*Templates, error) {
+	fsys, err := templateFS(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	layout, err := fs.ReadFile(fsys, "layout.html")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{
+		defaultLanguage: defaultLanguage,
+		text:            make(map[string]*texttemplate.Template),
+		html:            make(map[string]*htmltemplate.Template),
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		language := entry.Name()
+		t.languages = append(t.languages, language)
+
+		files, err := fs.ReadDir(fsys, language)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name, ext := strings.TrimSuffix(file.Name(), path.Ext(file.Name())), path.Ext(file.Name())
+			content, err := fs.ReadFile(fsys, path.Join(language, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			key := language + "/" + name
+			switch ext {
+			case ".txt":
+				t.text[key], err = texttemplate.New(name).Option("missingkey=error").Parse(string(content))
+			case ".html":
+				var tmpl *htmltemplate.Template
+				tmpl, err = htmltemplate.New("layout").Parse(string(layout))
+				if err == nil {
+					tmpl, err = tmpl.Parse(string(content))
+				}
+				t.html[key] = tmpl
+			default:
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse email template %s%s: %w", key, ext, err)
+			}
+		}
+	}
+
+	if !slices.Contains(t.languages, defaultLanguage) {
+		return nil, fmt.Errorf("no email templates for the default language %q", defaultLanguage)
+	}
+	return t, nil
+}
+
+// templateFS returns the embedded templates, overridden by the files in the directory if it is not empty
+func templateFS(dir string) (fs.FS, error) {
+	embedded, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return embedded, nil
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("invalid email template directory: %w", err)
+	}
+	return overlayFS{dir: dir, base: embedded}, nil
+}
+
+// overlayFS reads files from the directory first, and from the base if they are not there
+type overlayFS struct {
+	dir  string
+	base fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := os.DirFS(o.dir).Open(name)
+	if err == nil {
+		return file, nil
+	}
+	return o.base.Open(name)
+}
+
+// ReadDir lists the entries of both, so that the directory can add languages and templates
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, baseErr := fs.ReadDir(o.base, name)
+	overrides, err := os.ReadDir(filepath.Join(o.dir, filepath.FromSlash(name)))
+	if err != nil && baseErr != nil {
+		return nil, baseErr
+	}
+
+	for _, override := range overrides {
+		if !slices.ContainsFunc(entries, func(entry fs.DirEntry) bool { return entry.Name() == override.Name() }) {
+			entries = append(entries, override)
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// MatchLanguage returns the first language with templates in the list of preferred languages, such as an Accept-Language header
+// Returns the default language if there is none
+func (t *Templates) MatchLanguage(preferred string) string {
+	for _, tag := range visitor.ParseAcceptLanguage(preferred) {
+		for _, language := range t.languages {
+			if visitor.MatchLanguage(tag, language) {
+				return language
+			}
+		}
+	}
+	return t.defaultLanguage
+}
+
+// Render renders the subject and the plain text and HTML bodies of the template in the language
+// Each file missing in the language falls back to the one of the default language, so a directory may only override the .txt or the .html
+func (t *Templates) Render(name string, language string, data any) (subject string, text string, html string, err error) {
+	language = t.MatchLanguage(language)
+	textTemplate, ok := t.text[language+"/"+name]
+	if !ok {
+		textTemplate, ok = t.text[t.defaultLanguage+"/"+name]
+	}
+	htmlTemplate, htmlOK := t.html[language+"/"+name]
+	if !htmlOK {
+		htmlTemplate, htmlOK = t.html[t.defaultLanguage+"/"+name]
+	}
+	if !ok || !htmlOK {
+		return "", "", "", fmt.Errorf("no email template %q", name)
+	}
+
+	var buf strings.Builder
+	if err := textTemplate.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := textTemplate.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	text = strings.TrimSpace(buf.String()) + "\n"
+
+	buf.Reset()
+	if err := htmlTemplate.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	html = buf.String()
+
+	if subject == "" {
+		return "", "", "", errors.New("email template " + name + " has no subject")
+	}
+	return subject, text, html, nil
+}
@@ -0,0 +1,231 @@
+package mailer
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var lockedData = AccountLockedData{
+	Username:    "alice",
+	Failures:    5,
+	ClientIP:    "203.0.113.7",
+	LockedUntil: time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC),
+}
+
+// writeFiles writes the files relative to a new temporary directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTemplatesRenderEmbedded(t *testing.T) {
+	templates, err := LoadTemplates("", "en")
+	if err != nil {
+		t.Fatalf("LoadTemplates() error = %v", err)
+	}
+
+	subject, text, html, err := templates.Render(TemplateAccountLocked, "en", lockedData)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if subject != "Your account was temporarily locked" {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(text, "Hello alice,") || !strings.Contains(text, "203.0.113.7") || strings.Contains(text, "subject") {
+		t.Errorf("text = %q", text)
+	}
+	if !strings.Contains(html, "<!DOCTYPE html>") || !strings.Contains(html, "<b>alice</b>") {
+		t.Errorf("html = %q, want the content in the layout", html)
+	}
+
+	// Every template of the embedded languages renders with its data
+	for _, language := range []string{"en", "zh"} {
+		for name, data := range map[string]any{
+			TemplateEmailCodeRegister:           EmailCodeData{Code: "123456"},
+			TemplateEmailCodeResetPassword:      EmailCodeData{Username: "alice", Code: "123456"},
+			TemplateEmailCodeChangeEmailCurrent: EmailChangeCodeData{Username: "alice", Code: "123456", NewEmail: "new@example.com"},
+			TemplateEmailCodeChangeEmailNew:     EmailChangeCodeData{Username: "alice", Code: "123456", NewEmail: "new@example.com"},
+			TemplateAccountLocked:               lockedData,
+			TemplateLinkBroken:                  LinkBrokenData{Username: "alice", ShortCode: "abc", OriginalURL: "https://example.com", StatusCode: 404},
+			TemplateLinkExpiring:                LinkExpiringData{Username: "alice", Links: []ExpiringLink{{ShortCode: "abc", ExpiredAt: lockedData.LockedUntil}}},
+		} {
+			if _, _, _, err := templates.Render(name, language, data); err != nil {
+				t.Errorf("Render(%q, %q) error = %v", name, language, err)
+			}
+		}
+	}
+}
+
+func TestTemplatesMatchLanguage(t *testing.T) {
+	templates, err := LoadTemplates("", "en")
+	if err != nil {
+		t.Fatalf("LoadTemplates() error = %v", err)
+	}
+
+	tests := []struct {
+		preferred string
+		want      string
+	}{
+		{preferred: "zh-CN,zh;q=0.9,en;q=0.8", want: "zh"},
+		{preferred: "fr-FR,en;q=0.5", want: "en"},
+		{preferred: "zh", want: "zh"},
+		{preferred: "fr", want: "en"},
+		{preferred: "", want: "en"},
+	}
+	for _, tt := range tests {
+		if got := templates.MatchLanguage(tt.preferred); got != tt.want {
+			t.Errorf("MatchLanguage(%q) = %q, want %q", tt.preferred, got, tt.want)
+		}
+	}
+}
+
+func TestTemplatesOverrideAndFallback(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		// Overrides only the HTML body of an embedded template
+		"en/account_locked.html": `{{define "content"}}<p>Custom {{.Username}}</p>{{end}}`,
+		// A new language with only the plain text of one template
+		"fr/account_locked.txt": "{{define \"subject\"}}Compte bloqué{{end}}Bonjour {{.Username}}",
+		// A new template
+		"en/welcome.txt":  `{{define "subject"}}Welcome{{end}}Hi {{.}}`,
+		"en/welcome.html": `{{define "content"}}<p>Hi {{.}}</p>{{end}}`,
+	})
+	templates, err := LoadTemplates(dir, "en")
+	if err != nil {
+		t.Fatalf("LoadTemplates() error = %v", err)
+	}
+
+	subject, text, html, err := templates.Render(TemplateAccountLocked, "en", lockedData)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if subject != "Your account was temporarily locked" || !strings.Contains(text, "Hello alice,") {
+		t.Errorf("subject = %q, text = %q, want the embedded plain text", subject, text)
+	}
+	if !strings.Contains(html, "<p>Custom alice</p>") || !strings.Contains(html, "<!DOCTYPE html>") {
+		t.Errorf("html = %q, want the override in the layout", html)
+	}
+
+	// The .txt of the language is used, the missing .html falls back to the default language on its own
+	subject, text, html, err = templates.Render(TemplateAccountLocked, "fr", lockedData)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if subject != "Compte bloqué" || text != "Bonjour alice\n" {
+		t.Errorf("subject = %q, text = %q, want the French plain text", subject, text)
+	}
+	if !strings.Contains(html, "<p>Custom alice</p>") {
+		t.Errorf("html = %q, want the HTML of the default language", html)
+	}
+
+	// Templates missing in the language altogether fall back to the default language
+	subject, _, _, err = templates.Render(TemplateLinkBroken, "fr", LinkBrokenData{Username: "alice"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if subject != "One of your short links is broken" {
+		t.Errorf("subject = %q, want the English subject", subject)
+	}
+
+	if _, _, html, err := templates.Render("welcome", "en", "bob"); err != nil || !strings.Contains(html, "<p>Hi bob</p>") {
+		t.Errorf("Render(welcome) html = %q, error = %v", html, err)
+	}
+	if _, _, _, err := templates.Render("missing", "en", nil); err == nil {
+		t.Error("Render() of a missing template error = nil, want an error")
+	}
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		name            string
+		dir             string
+		defaultLanguage string
+	}{
+		{name: "missing directory", dir: filepath.Join(t.TempDir(), "missing"), defaultLanguage: "en"},
+		{name: "no templates for the default language", defaultLanguage: "fr"},
+		{name: "invalid template", dir: writeFiles(t, map[string]string{"en/broken.txt": "{{.Username"}), defaultLanguage: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadTemplates(tt.dir, tt.defaultLanguage); err == nil {
+				t.Error("LoadTemplates() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestOverlayFS(t *testing.T) {
+	base := fstest.MapFS{
+		"layout.html": {Data: []byte("base layout")},
+		"en/a.txt":    {Data: []byte("base a")},
+		"en/b.txt":    {Data: []byte("base b")},
+	}
+	dir := writeFiles(t, map[string]string{
+		"en/b.txt": "override b",
+		"en/c.txt": "added c",
+		"fr/a.txt": "added fr",
+	})
+	overlay := overlayFS{dir: dir, base: base}
+
+	for name, want := range map[string]string{
+		"layout.html": "base layout",
+		"en/a.txt":    "base a",
+		"en/b.txt":    "override b",
+		"en/c.txt":    "added c",
+		"fr/a.txt":    "added fr",
+	} {
+		content, err := fs.ReadFile(overlay, name)
+		if err != nil {
+			t.Errorf("ReadFile(%q) error = %v", name, err)
+			continue
+		}
+		if string(content) != want {
+			t.Errorf("ReadFile(%q) = %q, want %q", name, content, want)
+		}
+	}
+	if _, err := fs.ReadFile(overlay, "en/missing.txt"); err == nil {
+		t.Error("ReadFile() of a missing file error = nil, want an error")
+	}
+
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{dir: ".", want: []string{"en", "fr", "layout.html"}},
+		{dir: "en", want: []string{"a.txt", "b.txt", "c.txt"}},
+		// Only in the directory
+		{dir: "fr", want: []string{"a.txt"}},
+	}
+	for _, tt := range tests {
+		entries, err := fs.ReadDir(overlay, tt.dir)
+		if err != nil {
+			t.Errorf("ReadDir(%q) error = %v", tt.dir, err)
+			continue
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ReadDir(%q) = %v, want %v", tt.dir, names, tt.want)
+		}
+	}
+	if _, err := fs.ReadDir(overlay, "de"); err == nil {
+		t.Error("ReadDir() of a missing directory error = nil, want an error")
+	}
+}
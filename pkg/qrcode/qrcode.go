@@ -0,0 +1,296 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ZureTz/shorter-url/config"
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+// Image formats of the rendered QR codes
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// Error correction levels, each one recovering more of a damaged or covered code
+var levels = map[string]goqrcode.RecoveryLevel{
+	"L": goqrcode.Low,
+	"M": goqrcode.Medium,
+	"Q": goqrcode.High,
+	"H": goqrcode.Highest,
+}
+
+var (
+	// Returned by Render for options it does not accept, wrapping the reason
+	ErrInvalidOptions = errors.New("invalid qr code options")
+	ErrNoLogo         = errors.New("no logo is configured")
+)
+
+// Options customize a QR code, zero values fall back to the configured defaults
+type Options struct {
+	// Width and height of the image in pixels
+	Size int
+	// Error correction level, one of L, M, Q and H
+	Level string
+	// Colors of the dark and light modules as RRGGBB hex values
+	Foreground string
+	Background string
+	// Width of the quiet zone around the code, in modules
+	Margin *int
+	// FormatPNG or FormatSVG
+	Format string
+	// Draw the configured logo in the center, which forces the highest error correction level
+	Logo bool
+}
+
+// Renderer renders QR codes as PNG or SVG images
+type Renderer struct {
+	conf config.QRCodeConfig
+
+	// Decoded logo for PNG images, and the original file for SVG images
+	logo        image.Image
+	logoDataURI string
+}
+
+// NewRenderer creates a renderer with the defaults of the config, loading the logo if one is configured
+func NewRenderer(c config.QRCodeConfig) (*Renderer, error) {
+	r := &Renderer{conf: c}
+
+	// Check the defaults early rather than failing on every request
+	if _, err := parseColor(c.Foreground); err != nil {
+		return nil, fmt.Errorf("invalid foreground color: %w", err)
+	}
+	if _, err := parseColor(c.Background); err != nil {
+		return nil, fmt.Errorf("invalid background color: %w", err)
+	}
+	if _, ok := levels[c.DefaultLevel]; !ok {
+		return nil, fmt.Errorf("invalid error correction level: %q", c.DefaultLevel)
+	}
+
+	if c.LogoFile != "" {
+		data, err := os.ReadFile(c.LogoFile)
+		if err != nil {
+			return nil, err
+		}
+		r.logo, _, err = image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode logo: %w", err)
+		}
+		r.logoDataURI = "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)
+	}
+
+	return r, nil
+}
+
+// ContentType returns the media type of images in the format
+func ContentType(format string) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Render encodes the content as a QR code image
+func (r *Renderer) Render(content string, opts Options) ([]byte, error) {
+	opts, err := r.withDefaults(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	foreground, err := parseColor(opts.Foreground)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid foreground color: %w", ErrInvalidOptions, err)
+	}
+	background, err := parseColor(opts.Background)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid background color: %w", ErrInvalidOptions, err)
+	}
+
+	code, err := goqrcode.New(content, levels[opts.Level])
+	if err != nil {
+		return nil, err
+	}
+	// The margin is drawn here, so it can be configured
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	if opts.Format == FormatSVG {
+		return r.renderSVG(bitmap, opts, foreground, background), nil
+	}
+	return r.renderPNG(bitmap, opts, foreground, background)
+}
+
+// withDefaults fills the options that were not given and checks the others
+func (r *Renderer) withDefaults(opts Options) (Options, error) {
+	if opts.Size == 0 {
+		opts.Size = r.conf.DefaultSize
+	}
+	if opts.Size < 1 || (r.conf.MaxSize > 0 && opts.Size > r.conf.MaxSize) {
+		return opts, fmt.Errorf("size must be between 1 and %d", r.conf.MaxSize)
+	}
+
+	if opts.Level == "" {
+		opts.Level = r.conf.DefaultLevel
+	}
+	opts.Level = strings.ToUpper(opts.Level)
+	if _, ok := levels[opts.Level]; !ok {
+		return opts, fmt.Errorf("invalid error correction level: %q", opts.Level)
+	}
+
+	if opts.Foreground == "" {
+		opts.Foreground = r.conf.Foreground
+	}
+	if opts.Background == "" {
+		opts.Background = r.conf.Background
+	}
+
+	if opts.Margin == nil {
+		opts.Margin = &r.conf.Margin
+	}
+	if *opts.Margin < 0 {
+		return opts, errors.New("margin must not be negative")
+	}
+
+	if opts.Format == "" {
+		opts.Format = FormatPNG
+	}
+	if opts.Format != FormatPNG && opts.Format != FormatSVG {
+		return opts, fmt.Errorf("unsupported format: %q", opts.Format)
+	}
+
+	if opts.Logo {
+		if r.logo == nil {
+			return opts, ErrNoLogo
+		}
+		// The logo covers the center of the code, which only the highest level recovers reliably
+		opts.Level = "H"
+	}
+
+	return opts, nil
+}
+
+// logoSide returns the width and height of the logo, a share of the size of the code
+func (r *Renderer) logoSide(codeSide float64) float64 {
+	ratio := r.conf.LogoRatio
+	if ratio <= 0 || ratio > 0.3 {
+		ratio = 0.2
+	}
+	return codeSide * ratio
+}
+
+func (r *Renderer) renderPNG(bitmap [][]bool, opts Options, foreground, background color.RGBA) ([]byte, error) {
+	modules := len(bitmap) + 2**opts.Margin
+	// Modules must be whole pixels to stay sharp, the rest of the image is left as padding
+	moduleSize := max(opts.Size/modules, 1)
+	size := max(opts.Size, moduleSize*modules)
+	offset := (size - moduleSize*modules) / 2
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	dark := &image.Uniform{foreground}
+	for y, row := range bitmap {
+		for x, set := range row {
+			if !set {
+				continue
+			}
+			left := offset + (x+*opts.Margin)*moduleSize
+			top := offset + (y+*opts.Margin)*moduleSize
+			draw.Draw(img, image.Rect(left, top, left+moduleSize, top+moduleSize), dark, image.Point{}, draw.Src)
+		}
+	}
+
+	if opts.Logo {
+		// Clear the area behind the logo so it stands out from the modules
+		side := int(r.logoSide(float64(len(bitmap) * moduleSize)))
+		padding := moduleSize
+		area := image.Rect((size-side)/2, (size-side)/2, (size+side)/2, (size+side)/2)
+		draw.Draw(img, area.Inset(-padding), &image.Uniform{background}, image.Point{}, draw.Src)
+		draw.Draw(img, area, scale(r.logo, area.Dx(), area.Dy()), image.Point{}, draw.Over)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *Renderer) renderSVG(bitmap [][]bool, opts Options, foreground, background color.RGBA) []byte {
+	modules := len(bitmap) + 2**opts.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hexColor(background))
+
+	// All dark modules form a single path of unit squares
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hexColor(foreground))
+	for y, row := range bitmap {
+		for x, set := range row {
+			if set {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+*opts.Margin, y+*opts.Margin)
+			}
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if opts.Logo {
+		side := r.logoSide(float64(len(bitmap)))
+		position := (float64(modules) - side) / 2
+		fmt.Fprintf(&buf, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`,
+			formatFloat(position-1), formatFloat(position-1), formatFloat(side+2), formatFloat(side+2), hexColor(background))
+		fmt.Fprintf(&buf, `<image x="%s" y="%s" width="%s" height="%s" href="%s"/>`,
+			formatFloat(position), formatFloat(position), formatFloat(side), formatFloat(side), r.logoDataURI)
+	}
+
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
+
+// scale resizes the image with nearest-neighbor sampling
+func scale(src image.Image, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+	for y := range height {
+		for x := range width {
+			srcX := bounds.Min.X + x*bounds.Dx()/width
+			srcY := bounds.Min.Y + y*bounds.Dy()/height
+			dst.Set(x, y, src.At(srcX, srcY))
+		}
+	}
+	return dst
+}
+
+// parseColor parses a RRGGBB hex color, with or without the leading #
+func parseColor(value string) (color.RGBA, error) {
+	value = strings.TrimPrefix(value, "#")
+	if len(value) != 6 {
+		return color.RGBA{}, fmt.Errorf("expected RRGGBB, got %q", value)
+	}
+	rgb, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("expected RRGGBB, got %q", value)
+	}
+	return color.RGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, nil
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
@@ -0,0 +1,239 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+var testConfig = config.QRCodeConfig{
+	DefaultSize:  256,
+	DefaultLevel: "M",
+	Foreground:   "000000",
+	Background:   "ffffff",
+	Margin:       4,
+	MaxSize:      1024,
+}
+
+func newTestRenderer(t *testing.T, c config.QRCodeConfig) *Renderer {
+	t.Helper()
+	r, err := NewRenderer(c)
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+	return r
+}
+
+// withLogo returns the config with a small red PNG logo
+func withLogo(t *testing.T, c config.QRCodeConfig) config.QRCodeConfig {
+	t.Helper()
+	logo := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := range 8 {
+		for x := range 8 {
+			logo.Set(x, y, color.RGBA{R: 0xff, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, logo); err != nil {
+		t.Fatal(err)
+	}
+	c.LogoFile = filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(c.LogoFile, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestNewRendererRejectsInvalidDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *config.QRCodeConfig)
+	}{
+		{name: "foreground", modify: func(c *config.QRCodeConfig) { c.Foreground = "black" }},
+		{name: "background", modify: func(c *config.QRCodeConfig) { c.Background = "fff" }},
+		{name: "level", modify: func(c *config.QRCodeConfig) { c.DefaultLevel = "X" }},
+		{name: "missing logo file", modify: func(c *config.QRCodeConfig) { c.LogoFile = "/nonexistent/logo.png" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testConfig
+			tt.modify(&c)
+			if _, err := NewRenderer(c); err == nil {
+				t.Error("NewRenderer() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	r := newTestRenderer(t, testConfig)
+
+	opts, err := r.withDefaults(Options{})
+	if err != nil {
+		t.Fatalf("withDefaults() error = %v", err)
+	}
+	if opts.Size != 256 || opts.Level != "M" || opts.Foreground != "000000" || opts.Background != "ffffff" ||
+		*opts.Margin != 4 || opts.Format != FormatPNG {
+		t.Errorf("withDefaults() = %+v, want the configured defaults", opts)
+	}
+
+	opts, err = r.withDefaults(Options{Size: 512, Level: "q", Margin: intPtr(0), Format: FormatSVG})
+	if err != nil {
+		t.Fatalf("withDefaults() error = %v", err)
+	}
+	if opts.Size != 512 || opts.Level != "Q" || *opts.Margin != 0 || opts.Format != FormatSVG {
+		t.Errorf("withDefaults() = %+v, want the given options", opts)
+	}
+}
+
+func TestRenderInvalidOptions(t *testing.T) {
+	r := newTestRenderer(t, testConfig)
+
+	tests := []struct {
+		name string
+		opts Options
+		want error
+	}{
+		{name: "size above the maximum", opts: Options{Size: 2048}},
+		{name: "negative size", opts: Options{Size: -1}},
+		{name: "unknown level", opts: Options{Level: "X"}},
+		{name: "negative margin", opts: Options{Margin: intPtr(-1)}},
+		{name: "unsupported format", opts: Options{Format: "gif"}},
+		{name: "invalid foreground", opts: Options{Foreground: "red"}},
+		{name: "invalid background", opts: Options{Background: "zzzzzz"}},
+		{name: "logo without a configured logo", opts: Options{Logo: true}, want: ErrNoLogo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render("https://example.com/abc", tt.opts)
+			if !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("Render() error = %v, want ErrInvalidOptions", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Render() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	r := newTestRenderer(t, testConfig)
+
+	data, err := r.Render("https://example.com/abc", Options{Size: 300, Foreground: "#112233", Background: "ddeeff"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	if bounds := img.Bounds(); bounds.Dx() != 300 || bounds.Dy() != 300 {
+		t.Errorf("image is %dx%d, want 300x300", bounds.Dx(), bounds.Dy())
+	}
+
+	// The corner is in the quiet zone, the finder pattern starts right after the margin
+	if got := color.RGBAModel.Convert(img.At(0, 0)).(color.RGBA); got != (color.RGBA{R: 0xdd, G: 0xee, B: 0xff, A: 0xff}) {
+		t.Errorf("quiet zone color = %v, want the background", got)
+	}
+	found := false
+	for x := range 300 {
+		if color.RGBAModel.Convert(img.At(x, 150)).(color.RGBA) == (color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("no module has the foreground color")
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	r := newTestRenderer(t, testConfig)
+
+	data, err := r.Render("https://example.com/abc", Options{Format: FormatSVG, Foreground: "112233", Margin: intPtr(2)})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	svg := string(data)
+	for _, want := range []string{`<svg xmlns="http://www.w3.org/2000/svg" width="256" height="256"`, `fill="#ffffff"`, `<path fill="#112233"`, `</svg>`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg does not contain %q", want)
+		}
+	}
+	if strings.Contains(svg, "<image") {
+		t.Error("svg contains a logo without asking for it")
+	}
+}
+
+func TestRenderLogo(t *testing.T) {
+	r := newTestRenderer(t, withLogo(t, testConfig))
+
+	// The logo forces the highest error correction level
+	opts, err := r.withDefaults(Options{Level: "L", Logo: true})
+	if err != nil {
+		t.Fatalf("withDefaults() error = %v", err)
+	}
+	if opts.Level != "H" {
+		t.Errorf("Level = %q, want H", opts.Level)
+	}
+
+	data, err := r.Render("https://example.com/abc", Options{Logo: true})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	center := img.Bounds().Dx() / 2
+	if got := color.RGBAModel.Convert(img.At(center, center)).(color.RGBA); got != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("center color = %v, want the logo", got)
+	}
+
+	data, err = r.Render("https://example.com/abc", Options{Logo: true, Format: FormatSVG})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(string(data), `href="data:image/png;base64,`) {
+		t.Error("svg does not embed the logo")
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{value: "000000", want: color.RGBA{A: 0xff}},
+		{value: "#ff8000", want: color.RGBA{R: 0xff, G: 0x80, A: 0xff}},
+		{value: "ABCDEF", want: color.RGBA{R: 0xab, G: 0xcd, B: 0xef, A: 0xff}},
+		{value: "fff", wantErr: true},
+		{value: "gggggg", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseColor(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseColor(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseColor(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
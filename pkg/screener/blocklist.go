@@ -0,0 +1,124 @@
+package screener
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Host names found in hosts files that must never be blocked
+var hostsFileIgnoredNames = map[string]struct{}{
+	"localhost":             {},
+	"localhost.localdomain": {},
+	"local":                 {},
+	"broadcasthost":         {},
+	"ip6-localhost":         {},
+	"ip6-loopback":          {},
+	"0.0.0.0":               {},
+}
+
+// Blocklist flags URLs using local lists of domains and regular expressions
+type Blocklist struct {
+	domains  map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// NewBlocklist creates a blocklist from domains, regular expressions matched against the whole URL,
+// and files in hosts file format ("0.0.0.0 bad.example.com") or with one domain per line
+func NewBlocklist(domains []string, patterns []string, hostsFiles []string) (*Blocklist, error) {
+	b := &Blocklist{domains: make(map[string]struct{})}
+	for _, domain := range domains {
+		b.addDomain(domain)
+	}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocklist pattern %q: %w", pattern, err)
+		}
+		b.patterns = append(b.patterns, re)
+	}
+
+	for _, hostsFile := range hostsFiles {
+		if err := b.loadHostsFile(hostsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func (b *Blocklist) addDomain(domain string) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain != "" {
+		b.domains[domain] = struct{}{}
+	}
+}
+
+// loadHostsFile adds the host names of a hosts file, ignoring the IP addresses they point to
+func (b *Blocklist) loadHostsFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open hosts file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Strip comments
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Lines either list a single domain, or an address followed by host names
+		names := fields
+		if len(fields) > 1 {
+			names = fields[1:]
+		}
+		for _, name := range names {
+			if _, ignored := hostsFileIgnoredNames[strings.ToLower(name)]; !ignored {
+				b.addDomain(name)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read hosts file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Screen flags the URL if its host or one of its parent domains is blocked, or it matches a pattern
+func (b *Blocklist) Screen(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	// Check the host and each of its parent domains
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for domain := host; domain != ""; {
+		if _, ok := b.domains[domain]; ok {
+			return fmt.Sprintf("domain %s is blocklisted", domain), nil
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+
+	for _, pattern := range b.patterns {
+		if pattern.MatchString(rawURL) {
+			return "url matches a blocklisted pattern", nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,196 @@
+package screener
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Threat types looked up when none are configured
+var defaultThreatTypes = []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"}
+
+// Safe Browsing v4 threatMatches:find request and response bodies
+type lookupRequest struct {
+	Client struct {
+		ClientID string `json:"clientId"`
+	} `json:"client"`
+	ThreatInfo struct {
+		ThreatTypes      []string      `json:"threatTypes"`
+		PlatformTypes    []string      `json:"platformTypes"`
+		ThreatEntryTypes []string      `json:"threatEntryTypes"`
+		ThreatEntries    []threatEntry `json:"threatEntries"`
+	} `json:"threatInfo"`
+}
+
+type threatEntry struct {
+	URL string `json:"url"`
+}
+
+type lookupResponse struct {
+	Matches []struct {
+		ThreatType string `json:"threatType"`
+	} `json:"matches"`
+}
+
+type cachedVerdict struct {
+	reason    string
+	expiresAt time.Time
+}
+
+// Lookup flags URLs using a Safe-Browsing-style HTTP lookup API
+// Verdicts are cached in memory, so that redirects do not wait for the API every time
+type Lookup struct {
+	client      *http.Client
+	endpoint    string
+	apiKey      string
+	threatTypes []string
+	cacheTTL    time.Duration
+	failOpen    bool
+
+	mu    sync.Mutex
+	cache map[string]cachedVerdict
+}
+
+// NewLookup creates a lookup client for the endpoint, which may be a local stub
+// If failOpen is set, URLs are considered clean when the API cannot be reached
+func NewLookup(client *http.Client, endpoint string, apiKey string, threatTypes []string, cacheTTL time.Duration, failOpen bool) *Lookup {
+	if len(threatTypes) == 0 {
+		threatTypes = defaultThreatTypes
+	}
+
+	return &Lookup{
+		client:      client,
+		endpoint:    endpoint,
+		apiKey:      apiKey,
+		threatTypes: threatTypes,
+		cacheTTL:    cacheTTL,
+		failOpen:    failOpen,
+		cache:       make(map[string]cachedVerdict),
+	}
+}
+
+// Screen asks the lookup API whether the URL is a known threat
+func (l *Lookup) Screen(ctx context.Context, rawURL string) (string, error) {
+	if reason, ok := l.cachedVerdict(rawURL); ok {
+		return reason, nil
+	}
+
+	reason, err := l.lookup(ctx, rawURL)
+	if err != nil {
+		if l.failOpen {
+			log.Printf("url lookup failed, treating url as clean: %v", err)
+			return "", nil
+		}
+		return "", err
+	}
+
+	l.storeVerdict(rawURL, reason)
+	return reason, nil
+}
+
+func (l *Lookup) lookup(ctx context.Context, rawURL string) (string, error) {
+	var body lookupRequest
+	body.Client.ClientID = "shorter-url"
+	body.ThreatInfo.ThreatTypes = l.threatTypes
+	body.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	body.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+	body.ThreatInfo.ThreatEntries = []threatEntry{{URL: rawURL}}
+
+	content, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := l.endpoint
+	if l.apiKey != "" {
+		endpoint = addQueryParam(endpoint, "key", l.apiKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to query url lookup api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("url lookup api returned status %d", resp.StatusCode)
+	}
+
+	var result lookupResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode url lookup response: %w", err)
+	}
+
+	// No matches means the URL is not a known threat
+	if len(result.Matches) == 0 {
+		return "", nil
+	}
+
+	threatTypes := make([]string, 0, len(result.Matches))
+	for _, match := range result.Matches {
+		threatTypes = append(threatTypes, strings.ToLower(match.ThreatType))
+	}
+	return "flagged by url lookup as " + strings.Join(threatTypes, ", "), nil
+}
+
+func (l *Lookup) cachedVerdict(rawURL string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	verdict, ok := l.cache[rawURL]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(verdict.expiresAt) {
+		delete(l.cache, rawURL)
+		return "", false
+	}
+	return verdict.reason, true
+}
+
+func (l *Lookup) storeVerdict(rawURL string, reason string) {
+	if l.cacheTTL <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Drop expired verdicts from time to time, so that the cache does not grow without bound
+	now := time.Now()
+	if len(l.cache) >= 10000 {
+		for key, verdict := range l.cache {
+			if now.After(verdict.expiresAt) {
+				delete(l.cache, key)
+			}
+		}
+	}
+
+	l.cache[rawURL] = cachedVerdict{reason: reason, expiresAt: now.Add(l.cacheTTL)}
+}
+
+// addQueryParam adds a query parameter to the endpoint URL, keeping the existing ones
+func addQueryParam(endpoint string, key string, value string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
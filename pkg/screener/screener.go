@@ -0,0 +1,65 @@
+package screener
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+// Defaults of the lookup settings that are not configured
+// Redirects screen their destinations, so verdicts must be cached and slow lookups cut short
+const (
+	lookupDefaultTimeout  = 2 * time.Second
+	lookupDefaultCacheTTL = 30 * time.Minute
+)
+
+// Screener flags malicious or phishing URLs
+// Screen returns the reason the URL was flagged, or an empty string if it is clean
+type Screener interface {
+	Screen(ctx context.Context, rawURL string) (string, error)
+}
+
+// Chain runs the screeners in order and stops at the first one flagging the URL
+type Chain []Screener
+
+// Screen runs every screener of the chain until one flags the URL
+func (c Chain) Screen(ctx context.Context, rawURL string) (string, error) {
+	for _, screener := range c {
+		reason, err := screener.Screen(ctx, rawURL)
+		if err != nil {
+			return "", err
+		}
+		if reason != "" {
+			return reason, nil
+		}
+	}
+	return "", nil
+}
+
+// NewScreener creates the configured chain, with cheap local blocklists before the lookup API
+func NewScreener(c config.ScreenerConfig) (Chain, error) {
+	if !c.Enabled {
+		return Chain{}, nil
+	}
+
+	blocklist, err := NewBlocklist(c.BlockedDomains, c.BlockedPatterns, c.HostsFiles)
+	if err != nil {
+		return nil, err
+	}
+	chain := Chain{blocklist}
+
+	if c.LookupEndpoint != "" {
+		if c.LookupTimeout <= 0 {
+			c.LookupTimeout = lookupDefaultTimeout
+		}
+		if c.LookupCacheTTL <= 0 {
+			c.LookupCacheTTL = lookupDefaultCacheTTL
+		}
+		client := &http.Client{Timeout: c.LookupTimeout}
+		chain = append(chain, NewLookup(client, c.LookupEndpoint, c.LookupAPIKey, c.ThreatTypes, c.LookupCacheTTL, c.FailOpen))
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,145 @@
+package shortcode
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultAlphabet is the base62 character set used when no alphabet is configured
+const DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Alphabet profiles that can be selected in the configuration
+const (
+	ProfileBase62 = "base62"
+	// Base62 without the look-alike characters 0/O/o, 1/l/I and 5/S, suitable for printed materials
+	ProfileUnambiguous = "unambiguous"
+	// Lowercase letters and digits without look-alikes, for codes that are read out or typed on phones
+	ProfileLowercase = "lowercase"
+	// Use the alphabet given in the configuration
+	ProfileCustom = "custom"
+)
+
+var alphabetProfiles = map[string]string{
+	ProfileBase62:      DefaultAlphabet,
+	ProfileUnambiguous: "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRTUVWXYZ2346789",
+	ProfileLowercase:   "abcdefghijkmnpqrstuvwxyz2346789",
+}
+
+// AlphabetFromProfile returns the characters of the alphabet profile
+// The custom alphabet is only used by the custom profile, an empty profile defaults to base62
+func AlphabetFromProfile(profile string, custom string) (string, error) {
+	switch profile {
+	case "":
+		// Older configurations only set the alphabet itself
+		if custom != "" {
+			return custom, nil
+		}
+		return DefaultAlphabet, nil
+	case ProfileCustom:
+		if custom == "" {
+			return "", fmt.Errorf("custom alphabet profile requires an alphabet")
+		}
+		return custom, nil
+	}
+
+	chars, ok := alphabetProfiles[profile]
+	if !ok {
+		return "", fmt.Errorf("unknown alphabet profile: %s", profile)
+	}
+	return chars, nil
+}
+
+// Alphabet encodes numbers into fixed-length short codes using a set of characters
+type Alphabet struct {
+	chars  string
+	length int
+}
+
+// NewAlphabet creates an alphabet producing codes of the given length
+func NewAlphabet(chars string, length int) (*Alphabet, error) {
+	if len(chars) < 2 {
+		return nil, fmt.Errorf("alphabet must contain at least 2 characters")
+	}
+	if length <= 0 {
+		return nil, fmt.Errorf("short code length must be positive, got %d", length)
+	}
+
+	// Duplicated or non-ASCII characters would make the encoding ambiguous
+	seen := make(map[rune]struct{}, len(chars))
+	for _, char := range chars {
+		if char > math.MaxInt8 {
+			return nil, fmt.Errorf("alphabet contains non-ASCII character %q", char)
+		}
+		if _, ok := seen[char]; ok {
+			return nil, fmt.Errorf("alphabet contains duplicated character %q", char)
+		}
+		seen[char] = struct{}{}
+	}
+
+	return &Alphabet{chars: chars, length: length}, nil
+}
+
+// Size returns the number of characters in the alphabet
+func (a *Alphabet) Size() int {
+	return len(a.chars)
+}
+
+// Length returns the length of the generated short codes
+func (a *Alphabet) Length() int {
+	return a.length
+}
+
+// Keyspace returns the number of distinct codes, and false if it does not fit in an uint64
+func (a *Alphabet) Keyspace() (uint64, bool) {
+	keyspace := uint64(1)
+	for range a.length {
+		if keyspace > math.MaxUint64/uint64(len(a.chars)) {
+			return 0, false
+		}
+		keyspace *= uint64(len(a.chars))
+	}
+	return keyspace, true
+}
+
+// Encode converts the number into a short code, padded to the configured length
+func (a *Alphabet) Encode(n uint64) (string, error) {
+	base := uint64(len(a.chars))
+	code := make([]byte, a.length)
+	for i := a.length - 1; i >= 0; i-- {
+		code[i] = a.chars[n%base]
+		n /= base
+	}
+
+	// Leftover digits mean the number does not fit in the configured length
+	if n != 0 {
+		return "", fmt.Errorf("number is too large for a short code of length %d", a.length)
+	}
+	return string(code), nil
+}
+
+// Decode converts a short code back into the number it was encoded from
+func (a *Alphabet) Decode(code string) (uint64, error) {
+	if len(code) != a.length {
+		return 0, fmt.Errorf("short code must be %d characters long", a.length)
+	}
+
+	base := uint64(len(a.chars))
+	var n uint64
+	for i := range len(code) {
+		index := -1
+		for j := range len(a.chars) {
+			if a.chars[j] == code[i] {
+				index = j
+				break
+			}
+		}
+		if index < 0 {
+			return 0, fmt.Errorf("short code contains unknown character %q", code[i])
+		}
+		if n > (math.MaxUint64-uint64(index))/base {
+			return 0, fmt.Errorf("short code is out of range")
+		}
+		n = n*base + uint64(index)
+	}
+	return n, nil
+}
@@ -0,0 +1,106 @@
+package shortcode
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/bwmarrin/snowflake"
+)
+
+// Counter hands out unique, ever increasing numbers
+type Counter interface {
+	Next(ctx context.Context) (uint64, error)
+}
+
+// CounterFunc adapts a function to the Counter interface
+type CounterFunc func(ctx context.Context) (uint64, error)
+
+// Next calls the function
+func (f CounterFunc) Next(ctx context.Context) (uint64, error) {
+	return f(ctx)
+}
+
+// SnowflakeCounter produces Snowflake IDs, which are unique without coordination between nodes
+type SnowflakeCounter struct {
+	node *snowflake.Node
+}
+
+// NewSnowflakeCounter creates a Snowflake counter for the given node number
+func NewSnowflakeCounter(nodeNumber int) (*SnowflakeCounter, error) {
+	node, err := snowflake.NewNode(int64(nodeNumber))
+	if err != nil {
+		return nil, err
+	}
+	return &SnowflakeCounter{node: node}, nil
+}
+
+// Next generates the next Snowflake ID
+func (c *SnowflakeCounter) Next(ctx context.Context) (uint64, error) {
+	return uint64(c.node.Generate().Int64()), nil
+}
+
+// CounterGenerator turns the numbers of a counter into short codes
+// The numbers are obfuscated with a reversible permutation, so codes are unpredictable but never collide
+type CounterGenerator struct {
+	alphabet    *Alphabet
+	counter     Counter
+	permutation *Permutation
+	// Zero means the keyspace is larger than the uint64 range
+	keyspace uint64
+}
+
+// NewCounterGenerator creates a generator encoding the numbers of the counter with the alphabet
+func NewCounterGenerator(alphabet *Alphabet, counter Counter, permutationKey string) *CounterGenerator {
+	keyspace, ok := alphabet.Keyspace()
+	if !ok {
+		keyspace = 0
+	}
+
+	return &CounterGenerator{
+		alphabet:    alphabet,
+		counter:     counter,
+		permutation: NewPermutation(keyspace, []byte(permutationKey)),
+		keyspace:    keyspace,
+	}
+}
+
+// NewSnowflakeGenerator creates a counter generator backed by Snowflake IDs
+// Snowflake IDs use 63 bits, so the alphabet must be able to encode every one of them
+func NewSnowflakeGenerator(alphabet *Alphabet, nodeNumber int, permutationKey string) (*CounterGenerator, error) {
+	if keyspace, ok := alphabet.Keyspace(); ok && keyspace <= math.MaxInt64 {
+		return nil, fmt.Errorf("short code length %d is too short for snowflake IDs with %d characters", alphabet.Length(), alphabet.Size())
+	}
+
+	counter, err := NewSnowflakeCounter(nodeNumber)
+	if err != nil {
+		return nil, err
+	}
+	return NewCounterGenerator(alphabet, counter, permutationKey), nil
+}
+
+// GenerateShortCode takes the next number from the counter and encodes its permuted value
+func (g *CounterGenerator) GenerateShortCode(ctx context.Context) (string, error) {
+	n, err := g.counter.Next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get next short code number: %w", err)
+	}
+
+	if g.keyspace != 0 && n >= g.keyspace {
+		return "", fmt.Errorf("short code keyspace of %d codes is exhausted", g.keyspace)
+	}
+
+	return g.alphabet.Encode(g.permutation.Encrypt(n))
+}
+
+// DecodeShortCode reverses a generated short code back into the number it was generated from
+func (g *CounterGenerator) DecodeShortCode(code string) (uint64, error) {
+	n, err := g.alphabet.Decode(code)
+	if err != nil {
+		return 0, err
+	}
+	if g.keyspace != 0 && n >= g.keyspace {
+		return 0, fmt.Errorf("short code is out of range")
+	}
+	return g.permutation.Decrypt(n), nil
+}
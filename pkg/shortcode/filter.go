@@ -0,0 +1,152 @@
+package shortcode
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+var (
+	ErrReservedCode = errors.New("short code is reserved")
+	ErrBlockedCode  = errors.New("short code contains a blocked word")
+)
+
+//go:embed blocklist.txt
+var defaultBlocklist string
+
+//go:embed allowlist.txt
+var defaultAllowlist string
+
+// Codes that collide with paths served by the application, or that are likely to in the future
+var defaultReservedCodes = []string{
+	"admin", "api", "app", "assets", "debug", "favicon", "health", "login",
+	"logout", "metrics", "my-urls", "preview", "register", "robots", "static",
+}
+
+// Digits commonly used in place of letters, mapped back to the letters they stand for
+var leetReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "9", "g",
+)
+
+// Where the blocked words come from, named in the errors
+const (
+	sourceDefaultBlocklist = "built-in blocklist"
+	sourceConfigBlocklist  = "configured blocklist"
+	sourceBlocklistFile    = "blocklist file"
+)
+
+type blockedWord struct {
+	word   string
+	source string
+}
+
+// Filter rejects short codes that are reserved or contain blocked words
+// Blocked words are found anywhere in generated and custom codes, unless they are part of an allowed word like "analysis"
+type Filter struct {
+	blockedWords  []blockedWord
+	allowedWords  []string
+	reservedCodes map[string]struct{}
+}
+
+// NewFilter creates a filter from the default lists, extended by the configured blocklist, allowlist and reserved codes
+func NewFilter(c config.CodeGeneratorConfig) (*Filter, error) {
+	filter := &Filter{}
+	if !c.DisableDefaultBlocklist {
+		filter.addBlockedWords(parseWordList(defaultBlocklist), sourceDefaultBlocklist)
+	}
+	filter.addBlockedWords(c.Blocklist, sourceConfigBlocklist)
+
+	// Operators can maintain a longer list in a separate file
+	if c.BlocklistFile != "" {
+		content, err := os.ReadFile(c.BlocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read blocklist file: %w", err)
+		}
+		filter.addBlockedWords(parseWordList(string(content)), sourceBlocklistFile)
+	}
+
+	for _, word := range slices.Concat(parseWordList(defaultAllowlist), c.Allowlist) {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			filter.allowedWords = append(filter.allowedWords, word)
+		}
+	}
+	// Longer words first, so that "grapefruit" is allowed as a whole before "grape" is
+	slices.SortStableFunc(filter.allowedWords, func(a, b string) int { return len(b) - len(a) })
+
+	filter.reservedCodes = make(map[string]struct{})
+	for _, code := range slices.Concat(defaultReservedCodes, c.ReservedCodes) {
+		filter.reservedCodes[strings.ToLower(code)] = struct{}{}
+	}
+	return filter, nil
+}
+
+func (f *Filter) addBlockedWords(words []string, source string) {
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			f.blockedWords = append(f.blockedWords, blockedWord{word: word, source: source})
+		}
+	}
+}
+
+// Check returns an error naming the rule if the short code must not be used
+func (f *Filter) Check(code string) error {
+	lowered := strings.ToLower(code)
+	if err := f.checkReserved(lowered); err != nil {
+		return err
+	}
+
+	for _, candidate := range leetCandidates(lowered) {
+		candidate = f.maskAllowedWords(candidate)
+		for _, blocked := range f.blockedWords {
+			if strings.Contains(candidate, blocked.word) {
+				return fmt.Errorf("%w: contains %q of the %s", ErrBlockedCode, blocked.word, blocked.source)
+			}
+		}
+	}
+	return nil
+}
+
+// maskAllowedWords blanks out the allowed words, so that only the blocked words outside of them are found
+func (f *Filter) maskAllowedWords(candidate string) string {
+	for _, word := range f.allowedWords {
+		candidate = strings.ReplaceAll(candidate, word, strings.Repeat(" ", len(word)))
+	}
+	return candidate
+}
+
+func (f *Filter) checkReserved(lowered string) error {
+	if _, ok := f.reservedCodes[lowered]; ok {
+		return fmt.Errorf("%w: %q is used by the application or reserved by the operator", ErrReservedCode, lowered)
+	}
+	return nil
+}
+
+// leetCandidates returns the lowercase code as written and with the digits read as letters
+// "1" may stand for both "i" and "l"
+func leetCandidates(lowered string) []string {
+	return []string{
+		lowered,
+		leetReplacer.Replace(lowered),
+		leetReplacer.Replace(strings.ReplaceAll(lowered, "1", "l")),
+	}
+}
+
+// parseWordList reads one word per line, skipping empty lines and # comments
+func parseWordList(content string) []string {
+	var words []string
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words
+}
@@ -0,0 +1,78 @@
+package shortcode
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+func TestFilterCheck(t *testing.T) {
+	filter, err := NewFilter(config.CodeGeneratorConfig{
+		Blocklist:     []string{"spam"},
+		Allowlist:     []string{"spamalot"},
+		ReservedCodes: []string{"pricing"},
+	})
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{name: "clean generated code", code: "aB3xY9", want: nil},
+		{name: "reserved path", code: "Admin", want: ErrReservedCode},
+		{name: "configured reserved code", code: "pricing", want: ErrReservedCode},
+		{name: "blocked word inside", code: "xQfuckZ", want: ErrBlockedCode},
+		{name: "blocked word with digits", code: "ab5h1tc", want: ErrBlockedCode},
+		{name: "1 read as l", code: "zbo11ock", want: ErrBlockedCode},
+		{name: "blocked words run together", code: "fuckyou", want: ErrBlockedCode},
+		{name: "uppercase", code: "SHITLINK", want: ErrBlockedCode},
+		{name: "configured blocked word", code: "spamoffer", want: ErrBlockedCode},
+		{name: "analysis", code: "analysis", want: nil},
+		{name: "sussex", code: "sussex", want: nil},
+		{name: "homework", code: "homework", want: nil},
+		{name: "spice", code: "spice", want: nil},
+		{name: "raccoon", code: "raccoon", want: nil},
+		{name: "scrap", code: "Scrap", want: nil},
+		{name: "longer allowed word first", code: "grapefruit", want: nil},
+		{name: "configured allowed word", code: "spamalot", want: nil},
+		{name: "blocked word next to an allowed one", code: "scrapshit", want: ErrBlockedCode},
+		{name: "allowed word does not hide a blocked one around it", code: "sexsussex", want: ErrBlockedCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := filter.Check(tt.code); !errors.Is(err, tt.want) {
+				t.Errorf("Check(%q) error = %v, want %v", tt.code, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterErrorNamesRule(t *testing.T) {
+	filter, err := NewFilter(config.CodeGeneratorConfig{Blocklist: []string{"spam"}})
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "crapola", want: `short code contains a blocked word: contains "crap" of the built-in blocklist`},
+		{code: "spam1", want: `short code contains a blocked word: contains "spam" of the configured blocklist`},
+		{code: "login", want: `short code is reserved: "login" is used by the application or reserved by the operator`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			err := filter.Check(tt.code)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Check(%q) error = %v, want %q", tt.code, err, tt.want)
+			}
+		})
+	}
+}
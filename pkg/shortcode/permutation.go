@@ -0,0 +1,89 @@
+package shortcode
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+)
+
+const feistelRounds = 4
+
+// Permutation is a keyed, reversible one-to-one mapping of the numbers in [0, domain)
+// It is a balanced Feistel network, with cycle walking to stay inside the domain
+// Sequential numbers are mapped to numbers that look random without ever colliding
+type Permutation struct {
+	// Zero means the full uint64 range
+	domain   uint64
+	halfBits uint
+	mask     uint64
+	key      []byte
+}
+
+// NewPermutation creates a permutation of [0, domain) keyed with the secret key
+// A domain of zero permutes the full uint64 range
+func NewPermutation(domain uint64, key []byte) *Permutation {
+	// Number of bits needed to represent every number in the domain, rounded up to be even
+	width := uint(64)
+	if domain != 0 {
+		width = uint(bits.Len64(domain - 1))
+		width += width % 2
+		width = max(width, 2)
+	}
+
+	halfBits := width / 2
+	return &Permutation{
+		domain:   domain,
+		halfBits: halfBits,
+		mask:     (uint64(1) << halfBits) - 1,
+		key:      key,
+	}
+}
+
+// Encrypt maps the number to its permuted value
+func (p *Permutation) Encrypt(n uint64) uint64 {
+	// Cycle walking: keep permuting until the result falls back into the domain
+	for {
+		n = p.encryptBlock(n)
+		if p.domain == 0 || n < p.domain {
+			return n
+		}
+	}
+}
+
+// Decrypt maps the permuted value back to the original number
+func (p *Permutation) Decrypt(n uint64) uint64 {
+	for {
+		n = p.decryptBlock(n)
+		if p.domain == 0 || n < p.domain {
+			return n
+		}
+	}
+}
+
+func (p *Permutation) encryptBlock(n uint64) uint64 {
+	left, right := n>>p.halfBits, n&p.mask
+	for round := range feistelRounds {
+		left, right = right, left^p.roundFunction(round, right)
+	}
+	return left<<p.halfBits | right
+}
+
+func (p *Permutation) decryptBlock(n uint64) uint64 {
+	left, right := n>>p.halfBits, n&p.mask
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^p.roundFunction(round, left), left
+	}
+	return left<<p.halfBits | right
+}
+
+// roundFunction derives the pseudo-random round value from the key, the round number and the half block
+func (p *Permutation) roundFunction(round int, half uint64) uint64 {
+	var input [9]byte
+	input[0] = byte(round)
+	binary.BigEndian.PutUint64(input[1:], half)
+
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write(input[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil)) & p.mask
+}
@@ -0,0 +1,91 @@
+package shortcode
+
+import "testing"
+
+func TestPermutationIsBijection(t *testing.T) {
+	key := []byte("test key")
+
+	// Domains just below, at and above powers of two exercise the cycle walking
+	tests := []struct {
+		name   string
+		domain uint64
+	}{
+		{name: "smallest", domain: 1},
+		{name: "two", domain: 2},
+		{name: "power of four", domain: 16},
+		{name: "odd width", domain: 32},
+		{name: "just above a power of two", domain: 257},
+		{name: "just below a power of two", domain: 1023},
+		{name: "not a power of two", domain: 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPermutation(tt.domain, key)
+			seen := make(map[uint64]uint64, tt.domain)
+			for n := range tt.domain {
+				encrypted := p.Encrypt(n)
+				if encrypted >= tt.domain {
+					t.Fatalf("Encrypt(%d) = %d, outside the domain %d", n, encrypted, tt.domain)
+				}
+				if previous, ok := seen[encrypted]; ok {
+					t.Fatalf("Encrypt(%d) = Encrypt(%d) = %d", n, previous, encrypted)
+				}
+				seen[encrypted] = n
+				if decrypted := p.Decrypt(encrypted); decrypted != n {
+					t.Fatalf("Decrypt(Encrypt(%d)) = %d", n, decrypted)
+				}
+			}
+		})
+	}
+}
+
+func TestPermutationFullRange(t *testing.T) {
+	p := NewPermutation(0, []byte("test key"))
+	for _, n := range []uint64{0, 1, 1 << 32, 1<<63 + 12345, ^uint64(0)} {
+		if decrypted := p.Decrypt(p.Encrypt(n)); decrypted != n {
+			t.Errorf("Decrypt(Encrypt(%d)) = %d", n, decrypted)
+		}
+	}
+}
+
+func TestPermutationCycleWalkingBound(t *testing.T) {
+	// The block covers less than four times the domain, so walks are short on average
+	// A walk can never take more steps than there are numbers outside the domain
+	tests := []struct {
+		name   string
+		domain uint64
+	}{
+		{name: "just above a power of four", domain: 257},
+		{name: "just above a power of two", domain: 513},
+		{name: "not a power of two", domain: 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPermutation(tt.domain, []byte("test key"))
+			blockSize := uint64(1) << (2 * p.halfBits)
+			if blockSize >= 4*tt.domain {
+				t.Fatalf("block size %d is not below four times the domain %d", blockSize, tt.domain)
+			}
+
+			var totalSteps uint64
+			for n := range tt.domain {
+				steps := uint64(1)
+				for value := p.encryptBlock(n); value >= tt.domain; value = p.encryptBlock(value) {
+					steps++
+					if steps > blockSize-tt.domain+1 {
+						t.Fatalf("cycle walking from %d did not return to the domain", n)
+					}
+				}
+				totalSteps += steps
+			}
+
+			// Each number outside the domain is walked through by at most one walk,
+			// which keeps the average below four steps
+			if totalSteps > blockSize {
+				t.Errorf("total steps = %d, want at most %d", totalSteps, blockSize)
+			}
+		})
+	}
+}
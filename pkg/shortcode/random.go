@@ -0,0 +1,34 @@
+package shortcode
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// RandomGenerator picks every character of the short code with a cryptographically secure random source
+type RandomGenerator struct {
+	alphabet *Alphabet
+}
+
+// NewRandomGenerator creates a generator of random short codes using the alphabet
+func NewRandomGenerator(alphabet *Alphabet) *RandomGenerator {
+	return &RandomGenerator{alphabet: alphabet}
+}
+
+// GenerateShortCode creates a random short code, the caller is responsible for checking collisions
+func (g *RandomGenerator) GenerateShortCode(ctx context.Context) (string, error) {
+	charSetLength := big.NewInt(int64(g.alphabet.Size()))
+
+	shortCode := make([]byte, g.alphabet.Length())
+	for i := range shortCode {
+		// Generate a random index to select a character from the character set
+		index, err := rand.Int(rand.Reader, charSetLength)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate short code: %w", err)
+		}
+		shortCode[i] = g.alphabet.chars[index.Int64()]
+	}
+	return string(shortCode), nil
+}
@@ -1,25 +1,53 @@
 package shortcode
 
-import "math/rand"
+import (
+	"context"
+	"fmt"
 
-type ShortCodeGenerator struct {
-	shortCodeLength int
-}
+	"github.com/ZureTz/shorter-url/config"
+)
+
+// Strategies for generating short codes
+const (
+	StrategyRandom    = "random"
+	StrategyCounter   = "counter"
+	StrategySnowflake = "snowflake"
+)
 
-// Constructor for ShortCodeGenerator
-func NewShortCodeGenerator(shortCodeLength int) *ShortCodeGenerator {
-	return &ShortCodeGenerator{shortCodeLength: shortCodeLength}
+// Generator creates new short codes
+type Generator interface {
+	GenerateShortCode(ctx context.Context) (string, error)
 }
 
-const charSet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-const charSetLength = len(charSet)
+// NewShortCodeGenerator creates the generator for the configured strategy
+// The counter is only used by the counter strategy, and may be nil otherwise
+func NewShortCodeGenerator(c config.CodeGeneratorConfig, counter Counter) (Generator, error) {
+	chars, err := AlphabetFromProfile(c.AlphabetProfile, c.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+	alphabet, err := NewAlphabet(chars, c.ShortCodeLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a secret key anyone could decode the codes back into their numbers
+	if (c.Strategy == StrategyCounter || c.Strategy == StrategySnowflake) && c.PermutationKey == "" {
+		return nil, fmt.Errorf("%s strategy requires a permutation key", c.Strategy)
+	}
 
-// GenerateShortCode creates a short code of a specified length using a character set.
-func (g *ShortCodeGenerator) GenerateShortCode() string {
-	shortCode := make([]byte, g.shortCodeLength)
-	for i := range shortCode {
-		// Generate a random index to select a character from the character set
-		shortCode[i] = charSet[rand.Intn(charSetLength)]
+	switch c.Strategy {
+	// Random codes are the default for backward compatibility
+	case StrategyRandom, "":
+		return NewRandomGenerator(alphabet), nil
+	case StrategyCounter:
+		if counter == nil {
+			return nil, fmt.Errorf("counter strategy requires a counter")
+		}
+		return NewCounterGenerator(alphabet, counter, c.PermutationKey), nil
+	case StrategySnowflake:
+		return NewSnowflakeGenerator(alphabet, c.SnowflakeNodeNumber, c.PermutationKey)
+	default:
+		return nil, fmt.Errorf("unknown short code strategy: %s", c.Strategy)
 	}
-	return string(shortCode)
 }
@@ -0,0 +1,38 @@
+package shortcode
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ZureTz/shorter-url/config"
+)
+
+func TestNewShortCodeGenerator(t *testing.T) {
+	counter := CounterFunc(func(ctx context.Context) (uint64, error) { return 1, nil })
+
+	tests := []struct {
+		name    string
+		conf    config.CodeGeneratorConfig
+		counter Counter
+		wantErr bool
+	}{
+		{name: "random without key", conf: config.CodeGeneratorConfig{Strategy: StrategyRandom, ShortCodeLength: 7}},
+		{name: "default strategy", conf: config.CodeGeneratorConfig{ShortCodeLength: 7}},
+		{name: "counter with key", conf: config.CodeGeneratorConfig{Strategy: StrategyCounter, ShortCodeLength: 7, PermutationKey: "secret"}, counter: counter},
+		{name: "counter without key", conf: config.CodeGeneratorConfig{Strategy: StrategyCounter, ShortCodeLength: 7}, counter: counter, wantErr: true},
+		{name: "counter without counter", conf: config.CodeGeneratorConfig{Strategy: StrategyCounter, ShortCodeLength: 7, PermutationKey: "secret"}, wantErr: true},
+		{name: "snowflake with key", conf: config.CodeGeneratorConfig{Strategy: StrategySnowflake, ShortCodeLength: 11, PermutationKey: "secret"}},
+		{name: "snowflake without key", conf: config.CodeGeneratorConfig{Strategy: StrategySnowflake, ShortCodeLength: 11}, wantErr: true},
+		{name: "snowflake with short codes", conf: config.CodeGeneratorConfig{Strategy: StrategySnowflake, ShortCodeLength: 10, PermutationKey: "secret"}, wantErr: true},
+		{name: "unknown strategy", conf: config.CodeGeneratorConfig{Strategy: "pool", ShortCodeLength: 7}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewShortCodeGenerator(tt.conf, tt.counter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewShortCodeGenerator() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
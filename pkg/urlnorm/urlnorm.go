@@ -0,0 +1,126 @@
+package urlnorm
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// DefaultTrackingParams are removed from the canonical form when no list is configured
+// A trailing "*" matches every parameter with that prefix
+var DefaultTrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid",
+	"mc_cid", "mc_eid", "igshid", "yclid", "_ga", "_gl", "ref_src",
+}
+
+// Ports that are implied by the scheme
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalizer turns URLs into a canonical form
+// so that different spellings of the same target can be recognized
+type Normalizer struct {
+	exactParams  map[string]struct{}
+	prefixParams []string
+}
+
+// NewNormalizer creates a normalizer removing the given tracking parameters
+func NewNormalizer(trackingParams []string) *Normalizer {
+	n := &Normalizer{exactParams: make(map[string]struct{})}
+	for _, param := range trackingParams {
+		param = strings.ToLower(param)
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			n.prefixParams = append(n.prefixParams, prefix)
+		} else {
+			n.exactParams[param] = struct{}{}
+		}
+	}
+	return n
+}
+
+// Normalize lowercases the scheme and host, encodes internationalized domain names as punycode,
+// strips default ports and tracking parameters, and sorts the query
+func (n *Normalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("url %q has no host", rawURL)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if defaultPorts[u.Scheme] == port {
+		port = ""
+	}
+	// Brackets are needed around IPv6 addresses
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	// "http://example.com" and "http://example.com/" are the same resource
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	u.RawQuery = n.normalizeQuery(u.Query())
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// normalizeHost lowercases the host and converts internationalized domain names to punycode
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	// IP addresses are left as they are
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+
+	asciiHost, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %w", host, err)
+	}
+	return asciiHost, nil
+}
+
+// normalizeQuery removes tracking parameters and encodes the rest sorted by key
+func (n *Normalizer) normalizeQuery(query url.Values) string {
+	for key := range query {
+		if n.isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	// Encode sorts the parameters by key
+	return query.Encode()
+}
+
+func (n *Normalizer) isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	if _, ok := n.exactParams[key]; ok {
+		return true
+	}
+	for _, prefix := range n.prefixParams {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
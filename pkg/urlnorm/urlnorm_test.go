@@ -0,0 +1,92 @@
+package urlnorm
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	n := NewNormalizer(DefaultTrackingParams)
+
+	tests := []struct {
+		name   string
+		rawURL string
+		want   string
+	}{
+		{name: "scheme and host are lowercased", rawURL: "HTTPS://Example.COM/Path", want: "https://example.com/Path"},
+		{name: "empty path becomes slash", rawURL: "https://example.com", want: "https://example.com/"},
+		{name: "trailing dot is removed", rawURL: "https://example.com./", want: "https://example.com/"},
+		{name: "surrounding spaces are trimmed", rawURL: "  https://example.com/  ", want: "https://example.com/"},
+		{name: "internationalized domain becomes punycode", rawURL: "https://bücher.example/", want: "https://xn--bcher-kva.example/"},
+		{name: "uppercase internationalized domain", rawURL: "https://BÜCHER.example/", want: "https://xn--bcher-kva.example/"},
+		{name: "default http port is removed", rawURL: "http://example.com:80/", want: "http://example.com/"},
+		{name: "default https port is removed", rawURL: "https://example.com:443/", want: "https://example.com/"},
+		{name: "other ports are kept", rawURL: "https://example.com:8443/", want: "https://example.com:8443/"},
+		{name: "https port on http is kept", rawURL: "http://example.com:443/", want: "http://example.com:443/"},
+		{name: "ipv6 address keeps brackets", rawURL: "http://[::1]:80/", want: "http://[::1]/"},
+		{name: "ipv6 address with port", rawURL: "http://[2001:DB8::1]:8080/", want: "http://[2001:db8::1]:8080/"},
+		{name: "query is sorted", rawURL: "https://example.com/?b=2&a=1&c=3", want: "https://example.com/?a=1&b=2&c=3"},
+		{name: "values of a key keep their order", rawURL: "https://example.com/?b=2&a=z&a=y", want: "https://example.com/?a=z&a=y&b=2"},
+		{name: "tracking prefix is removed", rawURL: "https://example.com/?utm_source=x&UTM_Medium=y&id=1", want: "https://example.com/?id=1"},
+		{name: "exact tracking parameters are removed", rawURL: "https://example.com/?fbclid=a&gclid=b&id=1", want: "https://example.com/?id=1"},
+		{name: "parameters sharing an exact name's prefix are kept", rawURL: "https://example.com/?gclid_extra=1", want: "https://example.com/?gclid_extra=1"},
+		{name: "only tracking parameters leave no query", rawURL: "https://example.com/p?utm_campaign=x", want: "https://example.com/p"},
+		{name: "empty query is dropped", rawURL: "https://example.com/p?", want: "https://example.com/p"},
+		{name: "fragment is kept", rawURL: "https://example.com/p?b=1&a=2#top", want: "https://example.com/p?a=2&b=1#top"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := n.Normalize(tt.rawURL)
+			if err != nil {
+				t.Fatalf("Normalize(%q) error = %v", tt.rawURL, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.rawURL, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	n := NewNormalizer(DefaultTrackingParams)
+
+	tests := []struct {
+		name   string
+		rawURL string
+	}{
+		{name: "no host", rawURL: "/just/a/path"},
+		{name: "unparsable", rawURL: "http://exa mple.com/"},
+		{name: "invalid internationalized domain", rawURL: "https://xn--a.example/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := n.Normalize(tt.rawURL); err == nil {
+				t.Errorf("Normalize(%q) = %q, want an error", tt.rawURL, got)
+			}
+		})
+	}
+}
+
+func TestNormalizerTrackingParams(t *testing.T) {
+	tests := []struct {
+		name           string
+		trackingParams []string
+		rawURL         string
+		want           string
+	}{
+		{name: "no tracking parameters", trackingParams: nil, rawURL: "https://example.com/?utm_source=x", want: "https://example.com/?utm_source=x"},
+		{name: "configured prefix", trackingParams: []string{"ref_*"}, rawURL: "https://example.com/?ref_a=1&ref=2", want: "https://example.com/?ref=2"},
+		{name: "configured names are case insensitive", trackingParams: []string{"SID"}, rawURL: "https://example.com/?sid=1&Sid=2&id=3", want: "https://example.com/?id=3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewNormalizer(tt.trackingParams).Normalize(tt.rawURL)
+			if err != nil {
+				t.Fatalf("Normalize(%q) error = %v", tt.rawURL, err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.rawURL, got, tt.want)
+			}
+		})
+	}
+}
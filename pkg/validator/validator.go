@@ -1,7 +1,9 @@
 package validator
 
 import (
+	"net/url"
 	"regexp"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -11,11 +13,17 @@ type URLValidator struct {
 	validator *validator.Validate
 }
 
+// Longest custom short code users can choose
+const maxCustomCodeLength = 10
+
 // NewValidator creates a new URLValidator instance
-func NewValidator() *URLValidator {
+// Existing short codes are as long as custom codes or the configured length of generated codes
+func NewValidator(shortCodeLength int) *URLValidator {
 	v := validator.New()
 	v.RegisterValidation("custom_username_validator", CustomUsernameValidator)
 	v.RegisterValidation("custom_password_validator", CustomPasswordValidator)
+	v.RegisterValidation("custom_deep_link_validator", CustomDeepLinkValidator)
+	v.RegisterValidation("custom_short_code_validator", NewShortCodeValidator(max(maxCustomCodeLength, shortCodeLength)))
 	return &URLValidator{validator: v}
 }
 
@@ -42,3 +50,32 @@ func CustomPasswordValidator(fl validator.FieldLevel) bool {
 	password := fl.Field().String()
 	return passwordRegex.MatchString(password)
 }
+
+// /^[a-zA-Z0-9._~-]+$/: matches the characters that need no escaping in a URL path, which custom alphabets may use
+var shortCodeRegex = regexp.MustCompile(`^[a-zA-Z0-9._~-]+$`)
+
+// NewShortCodeValidator checks that a short code is at most maxLength characters that need no escaping
+func NewShortCodeValidator(maxLength int) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		shortCode := fl.Field().String()
+		return len(shortCode) <= maxLength && shortCodeRegex.MatchString(shortCode)
+	}
+}
+
+// Schemes that run code or read local data instead of opening an app
+var forbiddenDeepLinkSchemes = map[string]bool{
+	"javascript": true,
+	"vbscript":   true,
+	"data":       true,
+	"file":       true,
+	"blob":       true,
+}
+
+// Check if the deep link is an absolute URI opening an app, such as myapp://product/42
+func CustomDeepLinkValidator(fl validator.FieldLevel) bool {
+	link, err := url.Parse(fl.Field().String())
+	if err != nil || link.Scheme == "" {
+		return false
+	}
+	return !forbiddenDeepLinkSchemes[strings.ToLower(link.Scheme)]
+}
@@ -0,0 +1,34 @@
+package validator
+
+import "testing"
+
+func TestShortCodeValidator(t *testing.T) {
+	type request struct {
+		ShortCode string `validate:"required,custom_short_code_validator"`
+	}
+
+	tests := []struct {
+		name            string
+		shortCodeLength int
+		shortCode       string
+		wantErr         bool
+	}{
+		{name: "generated code", shortCodeLength: 7, shortCode: "aB3xY9z"},
+		{name: "longest custom code", shortCodeLength: 7, shortCode: "abcdefghij"},
+		{name: "too long", shortCodeLength: 7, shortCode: "abcdefghijk", wantErr: true},
+		{name: "snowflake code", shortCodeLength: 11, shortCode: "aB3xY9zQ2wE"},
+		{name: "snowflake code too long", shortCodeLength: 11, shortCode: "aB3xY9zQ2wEr", wantErr: true},
+		{name: "custom alphabet characters", shortCodeLength: 7, shortCode: "a-b_c.d"},
+		{name: "path separator", shortCodeLength: 7, shortCode: "a/b", wantErr: true},
+		{name: "empty", shortCodeLength: 7, shortCode: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator(tt.shortCodeLength).Validate(&request{ShortCode: tt.shortCode})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) error = %v, want error %v", tt.shortCode, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package visitor
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mssola/useragent"
+)
+
+// Operating systems detected from the user agent
+const (
+	OSIOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+)
+
+// Kinds of devices detected from the user agent
+const (
+	DeviceMobile  = "mobile"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// Device describes the client that sent a request
+// OS is empty if it was not recognized
+type Device struct {
+	OS   string
+	Kind string
+}
+
+// ParseUserAgent detects the operating system and the kind of device from the User-Agent header
+func ParseUserAgent(header string) Device {
+	ua := useragent.New(header)
+
+	var device Device
+	switch {
+	case ua.Bot():
+		device.Kind = DeviceBot
+	case ua.Mobile():
+		device.Kind = DeviceMobile
+	default:
+		device.Kind = DeviceDesktop
+	}
+
+	name := ua.OSInfo().Name
+	switch platform := ua.Platform(); {
+	case platform == "iPhone" || platform == "iPad" || platform == "iPod" || platform == "iPod touch":
+		device.OS = OSIOS
+	case name == "Android":
+		device.OS = OSAndroid
+	case strings.HasPrefix(name, "Windows"):
+		device.OS = OSWindows
+	case name == "Mac OS X" || name == "macOS":
+		device.OS = OSMacOS
+	case strings.HasPrefix(name, "CrOS"):
+		device.OS = OSChromeOS
+	case name == "Linux":
+		device.OS = OSLinux
+	}
+
+	return device
+}
+
+// ParseAcceptLanguage returns the language tags of the Accept-Language header, most preferred first
+// Tags are lowercased, and the wildcard and tags with a zero weight are left out
+func ParseAcceptLanguage(header string) []string {
+	type weightedTag struct {
+		tag    string
+		weight float64
+	}
+
+	var tags []weightedTag
+	for part := range strings.SplitSeq(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		tags = append(tags, weightedTag{tag: tag, weight: weight})
+	}
+
+	// Keep the order of the header for equal weights
+	slices.SortStableFunc(tags, func(a, b weightedTag) int {
+		switch {
+		case a.weight > b.weight:
+			return -1
+		case a.weight < b.weight:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	languages := make([]string, 0, len(tags))
+	for _, t := range tags {
+		languages = append(languages, t.tag)
+	}
+	return languages
+}
+
+// MatchLanguage reports whether the language tag falls within the language range
+// For example the range "pt" matches the tags "pt" and "pt-br", while the range "pt-br" only matches "pt-br"
+func MatchLanguage(tag, languageRange string) bool {
+	tag = strings.ToLower(tag)
+	languageRange = strings.ToLower(languageRange)
+	return tag == languageRange || strings.HasPrefix(tag, languageRange+"-")
+}
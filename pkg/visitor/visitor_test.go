@@ -0,0 +1,104 @@
+package visitor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   Device
+	}{
+		{
+			name:   "iphone",
+			header: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+			want:   Device{OS: OSIOS, Kind: DeviceMobile},
+		},
+		{
+			name:   "android phone",
+			header: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			want:   Device{OS: OSAndroid, Kind: DeviceMobile},
+		},
+		{
+			name:   "windows desktop",
+			header: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			want:   Device{OS: OSWindows, Kind: DeviceDesktop},
+		},
+		{
+			name:   "mac desktop",
+			header: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+			want:   Device{OS: OSMacOS, Kind: DeviceDesktop},
+		},
+		{
+			name:   "linux desktop",
+			header: "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			want:   Device{OS: OSLinux, Kind: DeviceDesktop},
+		},
+		{
+			name:   "bot",
+			header: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want:   Device{Kind: DeviceBot},
+		},
+		{
+			name:   "empty header",
+			header: "",
+			want:   Device{Kind: DeviceDesktop},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseUserAgent(tt.header); got != tt.want {
+				t.Errorf("ParseUserAgent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{name: "empty", header: "", want: []string{}},
+		{name: "single tag", header: "de-DE", want: []string{"de-de"}},
+		{name: "sorted by weight", header: "en;q=0.5, de-CH, fr;q=0.8", want: []string{"de-ch", "fr", "en"}},
+		{name: "equal weights keep their order", header: "it, es, pt;q=1", want: []string{"it", "es", "pt"}},
+		{name: "wildcard and zero weight are left out", header: "*, nl;q=0, sv;q=0.1", want: []string{"sv"}},
+		{name: "invalid weight is left out", header: "da;q=abc, fi", want: []string{"fi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tt.header); !slices.Equal(got, tt.want) {
+				t.Errorf("ParseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	tests := []struct {
+		tag           string
+		languageRange string
+		want          bool
+	}{
+		{tag: "pt", languageRange: "pt", want: true},
+		{tag: "pt-br", languageRange: "pt", want: true},
+		{tag: "pt-BR", languageRange: "PT-br", want: true},
+		{tag: "pt", languageRange: "pt-br", want: false},
+		{tag: "pt-pt", languageRange: "pt-br", want: false},
+		{tag: "ptx", languageRange: "pt", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag+" in "+tt.languageRange, func(t *testing.T) {
+			if got := MatchLanguage(tt.tag, tt.languageRange); got != tt.want {
+				t.Errorf("MatchLanguage(%q, %q) = %v, want %v", tt.tag, tt.languageRange, got, tt.want)
+			}
+		})
+	}
+}